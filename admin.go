@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const ADMIN_TIMEOUT = 10 * time.Second
+
+// adminDeposit is a deposit as returned by the admin API
+type adminDeposit struct {
+	ID string `json:"id"`
+	*store.Deposit
+}
+
+// adminReview is the body of approve and reject requests
+type adminReview struct {
+	Operator string `json:"operator"`
+	Reason   string `json:"reason"`
+}
+
+// startAdmin serves the admin API if it is configured
+func startAdmin(conf *config.Config) {
+
+	if conf.AdminAddress == "" {
+		return
+	}
+
+	// without a token anyone who reaches the API can approve deposits and resume the breaker
+	if conf.AdminToken == "" {
+		if !isLoopback(conf.AdminAddress) {
+			log.WithField("prefix", "admin").Fatal("Admin API on ", conf.AdminAddress, " requires adminToken unless it listens on loopback")
+		}
+		log.WithField("prefix", "admin").Warn("Admin API on ", conf.AdminAddress, " is not protected by adminToken")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /approvals", listApprovals)
+	mux.HandleFunc("POST /approvals/{id}/approve", approveDeposit)
+	mux.HandleFunc("POST /approvals/{id}/reject", rejectDeposit)
+	mux.HandleFunc("GET /breaker", listPauses)
+	mux.HandleFunc("POST /breaker/{scope}/pause", pauseScope)
+	mux.HandleFunc("POST /breaker/{scope}/resume", resumeScope)
+
+	server := &http.Server{
+		Addr:              conf.AdminAddress,
+		Handler:           adminAuth(conf.AdminToken, mux),
+		ReadHeaderTimeout: ADMIN_TIMEOUT,
+		ReadTimeout:       ADMIN_TIMEOUT,
+		WriteTimeout:      ADMIN_TIMEOUT,
+	}
+
+	go func() {
+		log.WithField("prefix", "admin").Info("Serving admin API on ", conf.AdminAddress)
+		if err := server.ListenAndServe(); err != nil {
+			log.WithField("prefix", "admin").Fatal(err)
+		}
+	}()
+
+}
+
+// isLoopback returns true if the listen address only accepts local connections
+func isLoopback(address string) bool {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminAuth requires the bearer token on every request if it is set
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			adminError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listApprovals returns deposits awaiting approval
+func listApprovals(w http.ResponseWriter, r *http.Request) {
+
+	deposits := []adminDeposit{}
+	for _, d := range store.Ledger.GetDepositsByStatus(store.DepositAwaitingApproval) {
+		deposits = append(deposits, adminDeposit{d.ID(), d})
+	}
+
+	adminJSON(w, http.StatusOK, deposits)
+
+}
+
+// approveDeposit releases a deposit from the approval queue
+func approveDeposit(w http.ResponseWriter, r *http.Request) {
+
+	review, ok := readReview(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := store.Ledger.ApproveDeposit(r.PathValue("id"), review.Operator)
+	if err != nil {
+		adminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.WithField("prefix", "admin").Info("Deposit ", d.ID(), " approved by ", review.Operator)
+
+	adminJSON(w, http.StatusOK, adminDeposit{d.ID(), d})
+
+}
+
+// rejectDeposit removes a deposit from the approval queue
+func rejectDeposit(w http.ResponseWriter, r *http.Request) {
+
+	review, ok := readReview(w, r)
+	if !ok {
+		return
+	}
+
+	if review.Reason == "" {
+		review.Reason = "rejected by operator"
+	}
+
+	d, err := store.Ledger.RejectDeposit(r.PathValue("id"), review.Operator, review.Reason)
+	if err != nil {
+		adminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.WithField("prefix", "admin").Warn("Deposit ", d.ID(), " rejected by ", review.Operator, ": ", review.Reason)
+
+	adminJSON(w, http.StatusOK, adminDeposit{d.ID(), d})
+
+}
+
+// listPauses returns tripped circuit breakers
+func listPauses(w http.ResponseWriter, r *http.Request) {
+	adminJSON(w, http.StatusOK, store.Breaker.GetPauses())
+}
+
+// pauseScope trips the circuit breaker of the scope by hand
+func pauseScope(w http.ResponseWriter, r *http.Request) {
+
+	review, ok := readReview(w, r)
+	if !ok {
+		return
+	}
+
+	scope := strings.ToLower(r.PathValue("scope"))
+	if review.Reason == "" {
+		review.Reason = "paused by " + review.Operator
+	}
+
+	if _, err := store.Breaker.Trip(scope, review.Reason); err != nil {
+		adminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.WithField("prefix", "admin").Warn("Minting on ", scope, " paused by ", review.Operator, ": ", review.Reason)
+
+	adminJSON(w, http.StatusOK, store.Breaker.Paused(scope))
+
+}
+
+// resumeScope lifts the pause of the scope
+func resumeScope(w http.ResponseWriter, r *http.Request) {
+
+	review, ok := readReview(w, r)
+	if !ok {
+		return
+	}
+
+	pause, err := store.Breaker.Resume(strings.ToLower(r.PathValue("scope")))
+	if err != nil {
+		adminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.WithField("prefix", "admin").Warn("Minting on ", pause.Scope, " resumed by ", review.Operator, ", paused since ", pause.PausedAt.Format(time.RFC3339), ": ", pause.Reason)
+
+	adminJSON(w, http.StatusOK, pause)
+
+}
+
+// readReview decodes the optional review body, the operator defaults to the remote address
+func readReview(w http.ResponseWriter, r *http.Request) (*adminReview, bool) {
+
+	review := &adminReview{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(review); err != nil {
+			adminError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return nil, false
+		}
+	}
+
+	if review.Operator == "" {
+		review.Operator = r.RemoteAddr
+	}
+
+	return review, true
+
+}
+
+func adminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithField("prefix", "admin").Error(err)
+	}
+}
+
+func adminError(w http.ResponseWriter, status int, message string) {
+	adminJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestIsLoopback(t *testing.T) {
+
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"127.0.0.1:8090", true},
+		{"127.0.0.2:8090", true},
+		{"localhost:8090", true},
+		{"[::1]:8090", true},
+		{":8090", false},
+		{"0.0.0.0:8090", false},
+		{"[::]:8090", false},
+		{"10.0.0.1:8090", false},
+		{"admin.example.com:8090", false},
+		{"127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isLoopback(tt.address); got != tt.want {
+			t.Errorf("isLoopback(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const ATTESTATION_TIMEOUT = 10 * time.Second
+const ATTESTATION_DOMAIN_NAME = "AEVMBridge"
+const ATTESTATION_DOMAIN_VERSION = "1"
+const ATTESTATION_TRANSPORT_HTTP = "http"
+const ATTESTATION_TRANSPORT_DIRECTORY = "directory"
+
+// attestations collects M-of-N relayer signatures before mints, nil unless attestation is configured
+var attestations *attestor
+
+// attestation is the signature of a relayer over the EIP-712 digest of a bridge event, as exchanged between relayers
+type attestation struct {
+	Digest    common.Hash    `json:"digest"`
+	Signer    common.Address `json:"signer"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// attestationTransport exchanges attestations between relayers
+type attestationTransport interface {
+	// publish makes an own attestation available to the other relayers
+	publish(a *attestation) error
+	// collect returns the attestations of the digest known to the transport, they are not verified
+	collect(digest common.Hash) ([]*attestation, error)
+}
+
+// attestor signs own attestations and collects the ones of other relayers
+type attestor struct {
+	threshold int
+	attesters map[common.Address]bool
+	submit    bool
+	name      string
+	version   string
+	transport attestationTransport
+	mu        sync.Mutex
+	published map[common.Hash]bool // own attestations published since start
+}
+
+// newAttestor sets up the transport and publishes attestations recorded in the ledger again
+func newAttestor(dir string, conf *config.Attestation) (*attestor, error) {
+
+	if conf == nil {
+		return nil, nil
+	}
+
+	at := &attestor{
+		threshold: conf.Threshold,
+		attesters: make(map[common.Address]bool),
+		submit:    conf.Submit,
+		name:      conf.DomainName,
+		version:   conf.DomainVersion,
+		published: make(map[common.Hash]bool),
+	}
+	if at.name == "" {
+		at.name = ATTESTATION_DOMAIN_NAME
+	}
+	if at.version == "" {
+		at.version = ATTESTATION_DOMAIN_VERSION
+	}
+
+	for _, attester := range conf.Attesters {
+		if !common.IsHexAddress(attester) {
+			return nil, fmt.Errorf("invalid attester %s", attester)
+		}
+		at.attesters[common.HexToAddress(attester)] = true
+	}
+	if at.threshold < 1 || at.threshold > len(at.attesters) {
+		return nil, fmt.Errorf("attestation threshold must be between 1 and %d attesters, got %d", len(at.attesters), at.threshold)
+	}
+
+	switch conf.Transport {
+	case ATTESTATION_TRANSPORT_HTTP:
+		at.transport = newHTTPTransport(conf.Listen, conf.Peers)
+	case ATTESTATION_TRANSPORT_DIRECTORY:
+		if conf.Directory == "" {
+			return nil, errors.New("attestation directory is not set")
+		}
+		at.transport = &directoryTransport{dir: resolvePath(dir, conf.Directory)}
+	default:
+		return nil, fmt.Errorf("unknown attestation transport %q, use %s or %s", conf.Transport, ATTESTATION_TRANSPORT_HTTP, ATTESTATION_TRANSPORT_DIRECTORY)
+	}
+
+	// peers may not have collected attestations published before restart
+	for _, d := range store.Ledger.GetDepositsByStatus(store.DepositAttested) {
+		digest := common.HexToHash(d.AttestationDigest)
+		signer, err := evm.RecoverTypedData(digest.Bytes(), d.Attestation)
+		if err != nil {
+			return nil, fmt.Errorf("attestation of %s: %w", d.ID(), err)
+		}
+		if err := at.transport.publish(&attestation{Digest: digest, Signer: signer, Signature: d.Attestation}); err != nil {
+			return nil, err
+		}
+		at.published[digest] = true
+	}
+
+	log.WithField("prefix", "attestation").Info("Minting with ", at.threshold, " of ", len(at.attesters), " attestations over ", conf.Transport, ", submit=", at.submit)
+
+	return at, nil
+
+}
+
+// check verifies that the signers of the routes may attest, relayers that do not submit have nothing else to do
+func (at *attestor) check(routes []*route) error {
+
+	for _, r := range routes {
+		// mints of Safe bridges are approved by the Safe owners instead
+		if r.safe != nil || at.attesters[r.target.PublicKey] {
+			continue
+		}
+		if !at.submit {
+			return fmt.Errorf("signer %s of bridge %s on chain %d is not an attester", r.target.PublicKey.Hex(), r.bridge.Address, r.target.ChainID)
+		}
+		log.WithField("prefix", "attestation").Warn("Signer ", r.target.PublicKey.Hex(), " of bridge ", r.bridge.Address, " on chain ", r.target.ChainID, " is not an attester, submitting attestations of others only")
+	}
+
+	return nil
+
+}
+
+// attestation returns the message attested for the deposit and the contract verifying it
+func (r *route) attestation(d *store.Deposit) (*evm.Attestation, common.Address) {
+
+	contract := common.HexToAddress(r.bridge.GetMinter())
+	if r.direction == DIRECTION_WITHDRAWAL {
+		contract = common.HexToAddress(r.bridge.Address)
+	}
+
+	// fee mints are told apart from the deposit mint of the same event
+	kind := r.direction
+	if d.Kind != "" {
+		kind = d.Kind
+	}
+
+	return &evm.Attestation{
+		ChainID:  d.ChainID,
+		Bridge:   common.HexToAddress(r.bridge.Address),
+		TxHash:   common.HexToHash(d.TxHash),
+		LogIndex: d.LogIndex,
+		Kind:     kind,
+		Receiver: common.HexToAddress(d.Receiver),
+		Amount:   d.MintAmount,
+	}, contract
+
+}
+
+// attest publishes the own attestation of the deposit and returns M signatures once they are collected,
+// nil if the deposit waits for other relayers or this relayer does not submit
+func (at *attestor) attest(r *route, d *store.Deposit) ([][]byte, error) {
+
+	message, contract := r.attestation(d)
+	data := message.TypedData(at.name, at.version, r.target.ChainID, contract)
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, err
+	}
+	digest := common.BytesToHash(hash)
+
+	if err := at.sign(r, d, data, digest); err != nil {
+		return nil, err
+	}
+
+	// sign moved the deposit to attested, another relayer submits it
+	if !at.submit {
+		return nil, nil
+	}
+
+	signatures := at.collect(digest)
+	if len(signatures) < at.threshold {
+		log.WithField("prefix", "attestation").Debug(r.direction, " ", d.ID(), " has ", len(signatures), " of ", at.threshold, " attestations")
+		return nil, nil
+	}
+
+	return signatures[:at.threshold], nil
+
+}
+
+// sign publishes the own attestation once per start, it is signed only once and kept in the ledger
+func (at *attestor) sign(r *route, d *store.Deposit, data apitypes.TypedData, digest common.Hash) error {
+
+	if !at.attesters[r.target.PublicKey] {
+		return nil
+	}
+
+	at.mu.Lock()
+	published := at.published[digest]
+	at.mu.Unlock()
+	if published {
+		return nil
+	}
+
+	signature := d.Attestation
+	if len(signature) == 0 || d.AttestationDigest != digest.Hex() {
+		reserved, err := at.reserve(r, d, digest)
+		if err != nil {
+			return err
+		}
+		if signature, err = r.target.Signer.SignTypedData(data); err != nil {
+			at.release(reserved, d.Status, err)
+			return err
+		}
+		// record the attestation, it is published after
+		if _, err := store.Ledger.UpdateDeposit(reserved, reserved.Status, func(d *store.Deposit) {
+			d.Attestation = signature
+		}); err != nil {
+			return err
+		}
+		log.WithField("prefix", "attestation").Info("Attested ", r.direction, " ", d.ID(), " of ", d.MintAmount, " to ", d.Receiver, " as ", r.target.PublicKey.Hex())
+	}
+
+	if err := at.transport.publish(&attestation{Digest: digest, Signer: r.target.PublicKey, Signature: signature}); err != nil {
+		return err
+	}
+
+	at.mu.Lock()
+	at.published[digest] = true
+	at.mu.Unlock()
+
+	return nil
+
+}
+
+// reserve checks the caps and records the digest before signing, in M-of-N mode the caps of each attester are its whole defence.
+// The reserved deposit counts towards the caps from now on, relayers that do not submit move it to attested.
+func (at *attestor) reserve(r *route, d *store.Deposit, digest common.Hash) (*store.Deposit, error) {
+
+	capsMu.Lock()
+	defer capsMu.Unlock()
+
+	if err := r.checkCaps(d); err != nil {
+		return nil, err
+	}
+
+	return store.Ledger.UpdateDeposit(d, d.Status, func(u *store.Deposit) {
+		if !at.submit {
+			u.Status = store.DepositAttested
+		}
+		u.Attestation = nil
+		u.AttestationDigest = digest.Hex()
+		u.MintAmount = d.MintAmount
+		u.Dust = d.Dust
+		u.SubmittedAt = time.Now()
+	})
+
+}
+
+// release frees the cap usage of a reservation that was never signed
+func (at *attestor) release(reserved *store.Deposit, status store.DepositStatus, cause error) {
+
+	if _, err := store.Ledger.UpdateDeposit(reserved, reserved.Status, func(d *store.Deposit) {
+		d.Status = status
+		d.AttestationDigest = ""
+		d.Error = cause.Error()
+	}); err != nil {
+		log.WithField("prefix", "attestation").Error(err)
+	}
+
+}
+
+// collect returns verified signatures of distinct attesters ordered by signer address
+func (at *attestor) collect(digest common.Hash) [][]byte {
+
+	collected, err := at.transport.collect(digest)
+	if err != nil {
+		log.WithField("prefix", "attestation").Error("failed to collect attestations of ", digest.Hex(), ": ", err)
+	}
+
+	valid := make(map[common.Address][]byte)
+	for _, a := range collected {
+		if a.Digest != digest || !at.attesters[a.Signer] {
+			continue
+		}
+		signer, err := evm.RecoverTypedData(digest.Bytes(), a.Signature)
+		if err != nil || signer != a.Signer {
+			log.WithField("prefix", "attestation").Warn("Invalid attestation of ", digest.Hex(), " claimed by ", a.Signer.Hex())
+			continue
+		}
+		valid[signer] = a.Signature
+	}
+
+	signers := make([]common.Address, 0, len(valid))
+	for signer := range valid {
+		signers = append(signers, signer)
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		return bytes.Compare(signers[i].Bytes(), signers[j].Bytes()) < 0
+	})
+
+	signatures := make([][]byte, 0, len(signers))
+	for _, signer := range signers {
+		signatures = append(signatures, valid[signer])
+	}
+
+	return signatures
+
+}
+
+// httpTransport serves own attestations to peers and fetches theirs
+type httpTransport struct {
+	peers  []string
+	client *http.Client
+	mu     sync.RWMutex
+	own    map[common.Hash][]*attestation
+}
+
+// newHTTPTransport serves own attestations on listen if it is set
+func newHTTPTransport(listen string, peers []string) *httpTransport {
+
+	t := &httpTransport{
+		peers:  peers,
+		client: &http.Client{Timeout: ATTESTATION_TIMEOUT},
+		own:    make(map[common.Hash][]*attestation),
+	}
+
+	if listen == "" {
+		return t
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /attestations/{digest}", t.serve)
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: ATTESTATION_TIMEOUT,
+		ReadTimeout:       ATTESTATION_TIMEOUT,
+		WriteTimeout:      ATTESTATION_TIMEOUT,
+	}
+
+	go func() {
+		log.WithField("prefix", "attestation").Info("Serving attestations on ", listen)
+		if err := server.ListenAndServe(); err != nil {
+			log.WithField("prefix", "attestation").Fatal(err)
+		}
+	}()
+
+	return t
+
+}
+
+// serve returns own attestations of the digest, attestations are signed so they need no auth
+func (t *httpTransport) serve(w http.ResponseWriter, r *http.Request) {
+
+	t.mu.RLock()
+	own := append([]*attestation{}, t.own[common.HexToHash(r.PathValue("digest"))]...)
+	t.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(own)
+
+}
+
+func (t *httpTransport) publish(a *attestation) error {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, existing := range t.own[a.Digest] {
+		if existing.Signer == a.Signer {
+			return nil
+		}
+	}
+	t.own[a.Digest] = append(t.own[a.Digest], a)
+
+	return nil
+
+}
+
+// collect asks every peer, unreachable peers are skipped as long as others provide enough attestations
+func (t *httpTransport) collect(digest common.Hash) ([]*attestation, error) {
+
+	t.mu.RLock()
+	collected := append([]*attestation{}, t.own[digest]...)
+	t.mu.RUnlock()
+
+	for _, peer := range t.peers {
+		attestations, err := t.fetch(peer, digest)
+		if err != nil {
+			log.WithField("prefix", "attestation").Warn("Failed to fetch attestations from ", peer, ": ", err)
+			continue
+		}
+		collected = append(collected, attestations...)
+	}
+
+	return collected, nil
+
+}
+
+func (t *httpTransport) fetch(peer string, digest common.Hash) ([]*attestation, error) {
+
+	resp, err := t.client.Get(strings.TrimRight(peer, "/") + "/attestations/" + digest.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var attestations []*attestation
+	if err := json.NewDecoder(resp.Body).Decode(&attestations); err != nil {
+		return nil, err
+	}
+
+	return attestations, nil
+
+}
+
+// directoryTransport keeps attestations in a directory shared by relayers, one file per digest and signer
+type directoryTransport struct {
+	dir string
+}
+
+// publish writes the attestation atomically, readers never see partial files
+func (t *directoryTransport) publish(a *attestation) error {
+
+	dir := filepath.Join(t.dir, a.Digest.Hex())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".attestation.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, a.Signer.Hex()+".json"))
+
+}
+
+func (t *directoryTransport) collect(digest common.Hash) ([]*attestation, error) {
+
+	dir := filepath.Join(t.dir, digest.Hex())
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var collected []*attestation
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		a := &attestation{}
+		if err := json.Unmarshal(data, a); err != nil {
+			log.WithField("prefix", "attestation").Warn("Skipping malformed attestation ", filepath.Join(dir, entry.Name()), ": ", err)
+			continue
+		}
+		collected = append(collected, a)
+	}
+
+	return collected, nil
+
+}
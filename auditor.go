@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const AUDITOR_INTERVAL = 1 * time.Hour
+
+// AUDITOR_REORG_DEPTH is the number of blocks below the last scanned one whose events are kept to follow a reorg
+const AUDITOR_REORG_DEPTH = 10000
+
+// auditScan is the running sum of Deposit events of a bridge scanned on the source chain, persisted in the data store
+type auditScan struct {
+	route *route
+	store.AuditState
+}
+
+// auditor reconciles Deposit events on source chains with mints in the ledger and the supply of rebase tokens
+type auditor struct {
+	scans  []*auditScan
+	routes []*route
+}
+
+// bridgeAudit compares a bridge on the source chain with the ledger, amounts are summed up to ScannedTo
+type bridgeAudit struct {
+	Bridge    string
+	ChainID   int
+	ScannedTo uint64
+	OnChain   *big.Int // Deposit events on the source chain, in source token units
+	Observed  *big.Int // deposits recorded in the ledger, in source token units
+	Mintable  *big.Int // OnChain scaled to destination token units
+	Minted    *big.Int // mined mints of deposits and fees, in destination token units
+}
+
+// tokenAudit compares the supply of a rebase token with the ledger
+type tokenAudit struct {
+	Token       string
+	ChainID     int
+	Minted      *big.Int // mined mints of all bridges
+	Redeemed    *big.Int // Redeem events recorded in the ledger
+	TotalSupply *big.Int
+	tracked     bool // every bridge of the token records withdrawals, so Redeemed is complete
+}
+
+type auditReport struct {
+	Bridges       []*bridgeAudit
+	Tokens        []*tokenAudit
+	Discrepancies []string
+}
+
+// newAuditor audits the bridges of the deposit routes
+func newAuditor(routes []*route) *auditor {
+
+	a := &auditor{}
+	for _, r := range routes {
+		if r.direction != DIRECTION_DEPOSIT {
+			continue
+		}
+		a.routes = append(a.routes, r)
+		scan := &auditScan{route: r}
+		if state, exists := store.Data.GetAudit(r.chainID, r.address); exists {
+			scan.AuditState = *state
+		} else {
+			scan.reset()
+		}
+		a.scans = append(a.scans, scan)
+	}
+
+	return a
+
+}
+
+// auditBridges runs the audit periodically and alerts on discrepancies
+func auditBridges(a *auditor, die chan bool) {
+
+	for {
+
+		select {
+		default:
+
+			report, err := a.audit()
+			if err != nil {
+				log.WithField("prefix", "auditor").Error("audit failed: ", err)
+			} else {
+				for _, b := range report.Bridges {
+					log.WithField("prefix", "auditor").Info("Bridge ", b.Bridge, " on chain ", b.ChainID, " up to block ", b.ScannedTo, ": deposited=", b.OnChain, " observed=", b.Observed, " mintable=", b.Mintable, " minted=", b.Minted)
+				}
+				for _, t := range report.Tokens {
+					log.WithField("prefix", "auditor").Info("Token ", t.Token, " on chain ", t.ChainID, ": minted=", t.Minted, " redeemed=", t.Redeemed, " totalSupply=", t.TotalSupply)
+				}
+				for _, d := range report.Discrepancies {
+					log.WithField("prefix", "auditor").Error("ALERT: audit discrepancy: ", d)
+				}
+			}
+
+			time.Sleep(AUDITOR_INTERVAL)
+
+		case <-die:
+			return
+		}
+
+	}
+
+}
+
+// audit scans new Deposit events up to the relayer cursors and compares the totals
+func (a *auditor) audit() (*auditReport, error) {
+
+	report := &auditReport{}
+
+	for _, scan := range a.scans {
+		if err := scan.advance(); err != nil {
+			return nil, fmt.Errorf("bridge %s: %w", scan.route.bridge.Address, err)
+		}
+		report.Bridges = append(report.Bridges, scan.compare(report))
+	}
+
+	tokens, err := a.auditTokens(report)
+	if err != nil {
+		return nil, err
+	}
+	report.Tokens = tokens
+
+	return report, nil
+
+}
+
+// reset drops the scanned events and starts over from the start block
+func (scan *auditScan) reset() {
+
+	scan.Next = scan.route.startBlock
+	scan.Hash = ""
+	scan.Total = big.NewInt(0)
+	scan.Settled = scan.route.startBlock
+	scan.Events = make(map[string]store.AuditEvent)
+
+}
+
+// rewind drops the events from block number on, or everything if they are already settled
+func (scan *auditScan) rewind(number uint64) {
+
+	r := scan.route
+
+	if number < scan.Settled {
+		log.WithField("prefix", "auditor").Warn("Reorg on chain ", r.chainID, " below settled block ", scan.Settled, ", rescanning bridge ", r.bridge.Address, " from block ", r.startBlock)
+		scan.reset()
+		return
+	}
+
+	for id, event := range scan.Events {
+		if event.BlockNumber >= number {
+			scan.Total.Sub(scan.Total, event.Amount)
+			delete(scan.Events, id)
+		}
+	}
+	scan.Next = number
+	scan.Hash = ""
+
+}
+
+// settle keeps only the events of the last AUDITOR_REORG_DEPTH blocks, older ones stay in the total
+func (scan *auditScan) settle() {
+
+	if scan.Next < scan.Settled+AUDITOR_REORG_DEPTH {
+		return
+	}
+
+	scan.Settled = scan.Next - AUDITOR_REORG_DEPTH
+	for id, event := range scan.Events {
+		if event.BlockNumber < scan.Settled {
+			delete(scan.Events, id)
+		}
+	}
+
+}
+
+// advance sums Deposit events from the last scanned block up to the relayer cursor, rewinding when the chain reorged
+func (scan *auditScan) advance() error {
+
+	r := scan.route
+
+	end, err := store.Data.GetBlock(r.chainID, r.cursor)
+	if err != nil {
+		// the relayer has not scanned the bridge yet
+		return nil
+	}
+
+	// the relayer rewound to a common ancestor below the scanned blocks
+	if end+1 < scan.Next {
+		scan.rewind(end + 1)
+	}
+
+	// the last scanned block is no longer canonical
+	if scan.Hash != "" {
+		hash, err := r.source.GetBlockHash(scan.Next - 1)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(hash, scan.Hash) {
+			log.WithField("prefix", "auditor").Warn("Reorg on chain ", r.chainID, ": block ", scan.Next-1, " changed from ", scan.Hash, " to ", hash, ", rescanning bridge ", r.bridge.Address, " from block ", scan.Settled)
+			scan.rewind(scan.Settled)
+		}
+	}
+
+	for scan.Next <= end {
+
+		last := scan.Next + evm.EVM_EVENTS_LIMIT
+		if last > end {
+			last = end
+		}
+
+		events, err := r.source.GetDepositEvents(r.address, scan.Next, &last)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			id := strings.ToLower((&store.Deposit{ChainID: r.chainID, Address: r.address, TxHash: event.TxHash, LogIndex: event.LogIndex}).ID())
+			if _, exists := scan.Events[id]; exists {
+				continue
+			}
+			scan.Events[id] = store.AuditEvent{BlockNumber: event.BlockNumber, Amount: event.Amount}
+			scan.Total.Add(scan.Total, event.Amount)
+		}
+
+		scan.Next = last + 1
+
+		hash, err := r.source.GetBlockHash(last)
+		if err != nil {
+			return err
+		}
+		scan.Hash = hash
+		scan.settle()
+		store.Data.SetAudit(r.chainID, r.address, &scan.AuditState)
+
+	}
+
+	return nil
+
+}
+
+// compare checks the scanned events against the ledger up to the last scanned block
+func (scan *auditScan) compare(report *auditReport) *bridgeAudit {
+
+	r := scan.route
+
+	b := &bridgeAudit{
+		Bridge:   r.bridge.Address,
+		ChainID:  r.chainID,
+		OnChain:  new(big.Int).Set(scan.Total),
+		Observed: big.NewInt(0),
+		Minted:   big.NewInt(0),
+	}
+	if scan.Next > 0 {
+		b.ScannedTo = scan.Next - 1
+	}
+	b.Mintable, _ = r.convert(b.OnChain)
+
+	recorded := make(map[string]bool)
+	for _, d := range store.Ledger.GetBridgeDeposits(r.chainID, r.address) {
+		if d.BlockNumber >= scan.Next {
+			continue
+		}
+		if d.Status == store.DepositMined {
+			amount := d.MintAmount
+			if amount == nil {
+				amount = d.Amount
+			}
+			b.Minted.Add(b.Minted, amount)
+		}
+		if d.Kind != "" || d.Orphaned {
+			continue
+		}
+		b.Observed.Add(b.Observed, d.Amount)
+		// settled events are only checked in the totals
+		if d.BlockNumber < scan.Settled {
+			continue
+		}
+		recorded[strings.ToLower(d.ID())] = true
+		if _, exists := scan.Events[strings.ToLower(d.ID())]; !exists {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf("deposit %s of %s is in the ledger but not on chain %d", d.ID(), d.Amount, r.chainID))
+		}
+	}
+
+	for id := range scan.Events {
+		if !recorded[id] {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf("Deposit event %s is missing from the ledger", id))
+		}
+	}
+
+	if b.OnChain.Cmp(b.Observed) != 0 {
+		report.Discrepancies = append(report.Discrepancies, fmt.Sprintf("bridge %s on chain %d deposited %s, ledger observed %s", b.Bridge, b.ChainID, b.OnChain, b.Observed))
+	}
+	if b.Minted.Cmp(b.Mintable) > 0 {
+		report.Discrepancies = append(report.Discrepancies, fmt.Sprintf("bridge %s on chain %d minted %s, deposits cover %s", b.Bridge, b.ChainID, b.Minted, b.Mintable))
+	}
+
+	return b
+
+}
+
+// auditTokens compares the supply of each rebase token with mints and redeems in the ledger
+func (a *auditor) auditTokens(report *auditReport) ([]*tokenAudit, error) {
+
+	var tokens []*tokenAudit
+	index := make(map[string]*tokenAudit)
+
+	for _, r := range a.routes {
+
+		key := fmt.Sprintf("%d:%s", r.target.ChainID, strings.ToLower(r.bridge.RebaseToken))
+		t, exists := index[key]
+		if !exists {
+			supply, err := r.target.GetTokenTotalSupply(common.HexToAddress(r.bridge.RebaseToken))
+			if err != nil {
+				return nil, err
+			}
+			t = &tokenAudit{
+				Token:       r.bridge.RebaseToken,
+				ChainID:     r.target.ChainID,
+				Minted:      big.NewInt(0),
+				Redeemed:    big.NewInt(0),
+				TotalSupply: supply,
+				tracked:     true,
+			}
+			for _, d := range store.Ledger.GetBridgeDeposits(r.target.ChainID, r.bridge.RebaseToken) {
+				if d.Direction == DIRECTION_WITHDRAWAL && !d.Orphaned {
+					t.Redeemed.Add(t.Redeemed, d.Amount)
+				}
+			}
+			index[key] = t
+			tokens = append(tokens, t)
+		}
+
+		t.tracked = t.tracked && r.bridge.Withdrawals
+
+		for _, d := range store.Ledger.GetBridgeDeposits(r.chainID, r.address) {
+			if d.Status != store.DepositMined {
+				continue
+			}
+			amount := d.MintAmount
+			if amount == nil {
+				amount = d.Amount
+			}
+			t.Minted.Add(t.Minted, amount)
+		}
+
+	}
+
+	// the rebase token grows with rewards, but never below what the bridges minted and was not redeemed
+	for _, t := range tokens {
+		if !t.tracked {
+			continue
+		}
+		outstanding := new(big.Int).Sub(t.Minted, t.Redeemed)
+		if outstanding.Cmp(t.TotalSupply) > 0 {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf("token %s on chain %d has totalSupply %s, ledger minted %s and redeemed %s", t.Token, t.ChainID, t.TotalSupply, t.Minted, t.Redeemed))
+		}
+	}
+
+	return tokens, nil
+
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+func TestAuditScanRewind(t *testing.T) {
+
+	// events at blocks 100, 200 and 300 scanned up to block 400, settled below 150
+	scanned := func() *auditScan {
+		scan := &auditScan{route: &route{chainID: 1, startBlock: 50}}
+		scan.Next = 401
+		scan.Hash = "0xabc"
+		scan.Total = big.NewInt(1 + 20 + 300)
+		scan.Settled = 150
+		scan.Events = map[string]store.AuditEvent{
+			"b": {BlockNumber: 200, Amount: big.NewInt(20)},
+			"c": {BlockNumber: 300, Amount: big.NewInt(300)},
+		}
+		return scan
+	}
+
+	tests := []struct {
+		name   string
+		rewind uint64
+		next   uint64
+		total  int64
+		events int
+	}{
+		{name: "above every event", rewind: 350, next: 350, total: 321, events: 2},
+		{name: "drops the last event", rewind: 300, next: 300, total: 21, events: 1},
+		{name: "down to settled", rewind: 150, next: 150, total: 1, events: 0},
+		{name: "below settled rescans", rewind: 149, next: 50, total: 0, events: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := scanned()
+			scan.rewind(tt.rewind)
+			if scan.Next != tt.next {
+				t.Errorf("next = %d, want %d", scan.Next, tt.next)
+			}
+			if scan.Total.Int64() != tt.total {
+				t.Errorf("total = %s, want %d", scan.Total, tt.total)
+			}
+			if len(scan.Events) != tt.events {
+				t.Errorf("%d events, want %d", len(scan.Events), tt.events)
+			}
+			if scan.Hash != "" {
+				t.Errorf("hash = %s, want it cleared", scan.Hash)
+			}
+		})
+	}
+
+}
+
+func TestAuditScanSettle(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		next    uint64
+		settled uint64
+		events  int
+	}{
+		{name: "within the reorg depth", next: AUDITOR_REORG_DEPTH + 50, settled: 50, events: 3},
+		{name: "settles old events", next: AUDITOR_REORG_DEPTH + 250, settled: 250, events: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := &auditScan{route: &route{chainID: 1, startBlock: 50}}
+			scan.reset()
+			scan.Next = tt.next
+			for id, block := range map[string]uint64{"a": 100, "b": 200, "c": 300} {
+				scan.Events[id] = store.AuditEvent{BlockNumber: block, Amount: big.NewInt(1)}
+				scan.Total.Add(scan.Total, big.NewInt(1))
+			}
+			scan.settle()
+			if scan.Settled != tt.settled {
+				t.Errorf("settled = %d, want %d", scan.Settled, tt.settled)
+			}
+			if len(scan.Events) != tt.events {
+				t.Errorf("%d events, want %d", len(scan.Events), tt.events)
+			}
+			if scan.Total.Int64() != 3 {
+				t.Errorf("total = %s, want 3", scan.Total)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+// maxReverts is the number of reverted mints in a row that pause a bridge, 0 disables the check
+var maxReverts int
+
+var revertsMu sync.Mutex
+
+// reverts counts reverted mints in a row by scope, in memory only: the count starts over when the relayer restarts
+var reverts = make(map[string]int)
+
+// bridgeScope is the breaker scope shared by both directions of a bridge
+func bridgeScope(address string) string {
+	return "bridge:" + strings.ToLower(address)
+}
+
+// chainScope is the breaker scope of every bridge on a chain
+func chainScope(chainID int) string {
+	return fmt.Sprintf("chain:%d", chainID)
+}
+
+// depositScope is the breaker scope of the bridge that recorded the deposit
+func depositScope(d *store.Deposit) string {
+	if d.Bridge != "" {
+		return bridgeScope(d.Bridge)
+	}
+	return bridgeScope(d.Address)
+}
+
+// tripBreaker pauses the scope until an operator resumes it
+func tripBreaker(scope string, reason string) {
+
+	tripped, err := store.Breaker.Trip(scope, reason)
+	if err != nil {
+		log.WithField("prefix", "breaker").Error("ALERT: circuit breaker paused ", scope, " until resumed, failed to persist the pause: ", reason, ": ", err)
+		return
+	}
+
+	if tripped {
+		log.WithField("prefix", "breaker").Error("ALERT: circuit breaker paused ", scope, ": ", reason)
+	}
+
+}
+
+// recordRevert counts a reverted mint of the bridge and pauses it after maxReverts in a row
+func recordRevert(scope string) {
+
+	revertsMu.Lock()
+	reverts[scope]++
+	count := reverts[scope]
+	revertsMu.Unlock()
+
+	if maxReverts > 0 && count >= maxReverts {
+		tripBreaker(scope, fmt.Sprintf("%d mints reverted in a row", count))
+	}
+
+}
+
+// recordSuccess resets the revert count of the bridge
+func recordSuccess(scope string) {
+	revertsMu.Lock()
+	delete(reverts, scope)
+	revertsMu.Unlock()
+}
+
+// scopes returns breaker scopes that pause the route
+func (r *route) scopes() []string {
+	return []string{store.BREAKER_SCOPE_GLOBAL, bridgeScope(r.bridge.Address), chainScope(r.source.ChainID), chainScope(r.target.ChainID)}
+}
+
+// paused returns the pause of the route, nil if it may execute
+func (r *route) paused() *store.Pause {
+	if pause := store.Breaker.Paused(r.scopes()...); pause != nil {
+		return pause
+	}
+	return unverifiedPause(r.bridge.Address)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const CLI_USAGE = `usage:
+  approvals list                   list deposits awaiting approval
+  approvals approve <id>           approve a deposit
+  approvals reject <id> [reason]   reject a deposit
+  breaker list                     list paused scopes
+  breaker pause <scope> [reason]   pause minting on global, bridge:<address> or chain:<chainID>
+  breaker resume <scope>           resume minting
+  audit                            reconcile deposits, mints and token supply`
+
+// runCommand runs a CLI subcommand against the admin API of the running relayer
+func runCommand(dir string, args []string) error {
+
+	conf, err := config.NewConfig(dir)
+	if err != nil {
+		return err
+	}
+
+	// the audit reads the relayer files and the chains, it does not need the relayer running
+	if len(args) == 1 && args[0] == "audit" {
+		return runAudit(dir, conf)
+	}
+
+	if conf.AdminAddress == "" {
+		return fmt.Errorf("adminAddress is not configured")
+	}
+
+	cli := &adminClient{
+		endpoint: "http://" + conf.AdminAddress,
+		token:    conf.AdminToken,
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("unknown command\n%s", CLI_USAGE)
+	}
+
+	switch args[0] {
+	case "approvals":
+		return runApprovals(cli, args[1:])
+	case "breaker":
+		return runBreaker(cli, args[1:])
+	}
+
+	return fmt.Errorf("unknown command\n%s", CLI_USAGE)
+
+}
+
+// runApprovals lists and reviews deposits awaiting approval
+func runApprovals(cli *adminClient, args []string) error {
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		var deposits []adminDeposit
+		if err := cli.call(http.MethodGet, "/approvals", nil, &deposits); err != nil {
+			return err
+		}
+		printDeposits(deposits)
+	case args[0] == "approve" && len(args) == 2:
+		var deposit adminDeposit
+		if err := cli.call(http.MethodPost, "/approvals/"+url.PathEscape(args[1])+"/approve", &adminReview{Operator: currentOperator()}, &deposit); err != nil {
+			return err
+		}
+		fmt.Println("approved", deposit.ID)
+	case args[0] == "reject" && len(args) >= 2:
+		var deposit adminDeposit
+		review := &adminReview{Operator: currentOperator(), Reason: strings.Join(args[2:], " ")}
+		if err := cli.call(http.MethodPost, "/approvals/"+url.PathEscape(args[1])+"/reject", review, &deposit); err != nil {
+			return err
+		}
+		fmt.Println("rejected", deposit.ID)
+	default:
+		return fmt.Errorf("unknown command\n%s", CLI_USAGE)
+	}
+
+	return nil
+
+}
+
+// runBreaker lists, pauses and resumes circuit breakers
+func runBreaker(cli *adminClient, args []string) error {
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		var pauses []*store.Pause
+		if err := cli.call(http.MethodGet, "/breaker", nil, &pauses); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SCOPE\tPAUSED AT\tREASON")
+		for _, p := range pauses {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Scope, p.PausedAt.Format(time.RFC3339), p.Reason)
+		}
+		w.Flush()
+	case args[0] == "pause" && len(args) >= 2:
+		var pause store.Pause
+		review := &adminReview{Operator: currentOperator(), Reason: strings.Join(args[2:], " ")}
+		if err := cli.call(http.MethodPost, "/breaker/"+url.PathEscape(args[1])+"/pause", review, &pause); err != nil {
+			return err
+		}
+		fmt.Println("paused", pause.Scope)
+	case args[0] == "resume" && len(args) == 2:
+		var pause store.Pause
+		if err := cli.call(http.MethodPost, "/breaker/"+url.PathEscape(args[1])+"/resume", &adminReview{Operator: currentOperator()}, &pause); err != nil {
+			return err
+		}
+		fmt.Println("resumed", pause.Scope)
+	default:
+		return fmt.Errorf("unknown command\n%s", CLI_USAGE)
+	}
+
+	return nil
+
+}
+
+// runAudit runs a single audit and prints the report, failing if there are discrepancies
+func runAudit(dir string, conf *config.Config) error {
+
+	var err error
+
+	store.Data = store.NewDataStore(filepath.Join(dir, CACHE_FILE), conf)
+	store.EVM = store.NewEVMStore()
+	if store.Ledger, err = store.NewLedgerStore(filepath.Join(dir, LEDGER_FILE)); err != nil {
+		return err
+	}
+
+	// no signer is needed to read the chains
+	for _, v := range conf.EVMNetworks {
+		client, err := evm.NewEVMClient(v)
+		if err != nil {
+			return err
+		}
+		store.EVM.AddClient(client)
+	}
+
+	var routes []*route
+	for _, b := range conf.Bridges {
+		r, err := newDepositRoute(b)
+		if err != nil {
+			return fmt.Errorf("bridge %s: %w", b.Address, err)
+		}
+		routes = append(routes, r)
+	}
+
+	report, err := newAuditor(routes).audit()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRIDGE\tCHAIN\tSCANNED TO\tDEPOSITED\tOBSERVED\tMINTABLE\tMINTED")
+	for _, b := range report.Bridges {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", b.Bridge, b.ChainID, b.ScannedTo, b.OnChain, b.Observed, b.Mintable, b.Minted)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "TOKEN\tCHAIN\tMINTED\tREDEEMED\tTOTAL SUPPLY")
+	for _, t := range report.Tokens {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", t.Token, t.ChainID, t.Minted, t.Redeemed, t.TotalSupply)
+	}
+	w.Flush()
+
+	if len(report.Discrepancies) > 0 {
+		fmt.Println()
+		for _, d := range report.Discrepancies {
+			fmt.Println("DISCREPANCY:", d)
+		}
+		return fmt.Errorf("%d discrepancies found", len(report.Discrepancies))
+	}
+
+	fmt.Println("\nno discrepancies")
+
+	return nil
+
+}
+
+// adminClient calls the admin API
+type adminClient struct {
+	endpoint string
+	token    string
+}
+
+func (c *adminClient) call(method string, path string, body interface{}, result interface{}) error {
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.endpoint+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	client := &http.Client{Timeout: ADMIN_TIMEOUT}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr["error"] == "" {
+			return fmt.Errorf("admin API returned %s", resp.Status)
+		}
+		return fmt.Errorf("%s", apiErr["error"])
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+
+}
+
+// printDeposits prints deposits as a table
+func printDeposits(deposits []adminDeposit) {
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAMOUNT\tMINT AMOUNT\tRECEIVER\tREASON")
+	for _, d := range deposits {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.ID, d.Amount, d.MintAmount, d.Receiver, d.Error)
+	}
+	w.Flush()
+
+}
+
+// currentOperator names the operator running CLI commands
+func currentOperator() string {
+	if usr, err := user.Current(); err == nil {
+		return usr.Username
+	}
+	return "cli"
+}
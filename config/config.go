@@ -14,21 +14,71 @@ import (
 )
 
 type Config struct {
-	PrivateKey  string      `yaml:"privateKey" json:"privateKey" form:"privateKey" query:"privateKey"`
-	EVMNetworks EVMNetworks `yaml:"evmNetworks" json:"evmNetworks" form:"evmNetworks" query:"evmNetworks"`
-	Bridges     []Bridge    `yaml:"bridges" json:"bridges" form:"bridges" query:"bridges"`
+	PrivateKey          string       `yaml:"privateKey" json:"privateKey" form:"privateKey" query:"privateKey"`                                     // plaintext hex key, refused unless the relayer runs with -insecure-plaintext-key
+	Keystore            string       `yaml:"keystore" json:"keystore" form:"keystore" query:"keystore"`                                             // V3 JSON keystore file of the signer, relative to the config dir
+	PassphraseFile      string       `yaml:"passphraseFile" json:"passphraseFile" form:"passphraseFile" query:"passphraseFile"`                     // file with the keystore passphrase, AEVM_KEYSTORE_PASSPHRASE or a prompt are used if not set
+	RemoteSigner        string       `yaml:"remoteSigner" json:"remoteSigner" form:"remoteSigner" query:"remoteSigner"`                             // endpoint of a Clef or web3signer compatible signer, replaces keystore if set
+	RemoteSignerAddress string       `yaml:"remoteSignerAddress" json:"remoteSignerAddress" form:"remoteSignerAddress" query:"remoteSignerAddress"` // account signing on the remote signer
+	EVMNetworks         EVMNetworks  `yaml:"evmNetworks" json:"evmNetworks" form:"evmNetworks" query:"evmNetworks"`
+	Bridges             []Bridge     `yaml:"bridges" json:"bridges" form:"bridges" query:"bridges"`
+	DailyCap            string       `yaml:"dailyCap" json:"dailyCap" form:"dailyCap" query:"dailyCap"`                     // rolling 24h cap of mints, and separately of releases, of each destination token across all bridges, in token units, no cap by default
+	AdminAddress        string       `yaml:"adminAddress" json:"adminAddress" form:"adminAddress" query:"adminAddress"`     // listen address of the admin API, e.g. 127.0.0.1:8090, disabled if not set
+	AdminToken          string       `yaml:"adminToken" json:"adminToken" form:"adminToken" query:"adminToken"`             // bearer token required by the admin API, may only be empty on loopback
+	DenylistFile        string       `yaml:"denylistFile" json:"denylistFile" form:"denylistFile" query:"denylistFile"`     // receivers never minted to without approval, one address per line, relative to the config dir
+	AllowlistFile       string       `yaml:"allowlistFile" json:"allowlistFile" form:"allowlistFile" query:"allowlistFile"` // only these receivers are minted to without approval if set
+	MaxReverts          int          `default:"3" yaml:"maxReverts" json:"maxReverts" form:"maxReverts" query:"maxReverts"` // reverted mints in a row that pause the bridge, counted since the relayer started
+	Attestation         *Attestation `yaml:"attestation" json:"attestation" form:"attestation" query:"attestation"`         // M-of-N relayer attestations of every mint and release, disabled if not set
 }
 
+// Attestation configures the threshold mode, relayers sign EIP-712 attestations of bridge events and mints carry M of them
+type Attestation struct {
+	Threshold     int      `yaml:"threshold" json:"threshold" form:"threshold" query:"threshold"`                 // M, signatures required per mint
+	Attesters     []string `yaml:"attesters" json:"attesters" form:"attesters" query:"attesters"`                 // N, signer addresses of the relayers accepted by the minter
+	Submit        bool     `yaml:"submit" json:"submit" form:"submit" query:"submit"`                             // submit mints once M attestations are collected, otherwise only attest
+	DomainName    string   `yaml:"domainName" json:"domainName" form:"domainName" query:"domainName"`             // EIP-712 domain name, AEVMBridge by default
+	DomainVersion string   `yaml:"domainVersion" json:"domainVersion" form:"domainVersion" query:"domainVersion"` // EIP-712 domain version, 1 by default
+	Transport     string   `yaml:"transport" json:"transport" form:"transport" query:"transport"`                 // http or directory
+	Listen        string   `yaml:"listen" json:"listen" form:"listen" query:"listen"`                             // http: listen address serving own attestations to peers, e.g. 0.0.0.0:8091
+	Peers         []string `yaml:"peers" json:"peers" form:"peers" query:"peers"`                                 // http: base URLs of the other relayers
+	Directory     string   `yaml:"directory" json:"directory" form:"directory" query:"directory"`                 // directory: shared directory of attestations, relative to the config dir
+}
+
+// Signer is the key signing txs, exactly one of privateKey, keystore and remoteSigner is set
+type Signer struct {
+	PrivateKey          string `yaml:"privateKey" json:"privateKey" form:"privateKey" query:"privateKey"`                                     // plaintext hex key, refused unless the relayer runs with -insecure-plaintext-key
+	Keystore            string `yaml:"keystore" json:"keystore" form:"keystore" query:"keystore"`                                             // V3 JSON keystore file, relative to the config dir
+	PassphraseFile      string `yaml:"passphraseFile" json:"passphraseFile" form:"passphraseFile" query:"passphraseFile"`                     // file with the keystore passphrase, AEVM_KEYSTORE_PASSPHRASE or a prompt are used if not set
+	RemoteSigner        string `yaml:"remoteSigner" json:"remoteSigner" form:"remoteSigner" query:"remoteSigner"`                             // endpoint of a Clef or web3signer compatible signer
+	RemoteSignerAddress string `yaml:"remoteSignerAddress" json:"remoteSignerAddress" form:"remoteSignerAddress" query:"remoteSignerAddress"` // account signing on the remote signer
+}
+
+// defaults of EVMNetwork fields where 0 is a valid setting, configor would replace an explicit 0 with a default tag
+const DEFAULT_GAS_TIP_CAP = 0.1
+const DEFAULT_REPLACE_AFTER = 180
+const DEFAULT_FEE_HISTORY_PERCENTILE = 50
+
 type EVMNetworks []EVMNetwork
 
 type EVMNetwork struct {
-	ChainID   int             `required:"true" yaml:"chainID" json:"chainID" form:"chainID" query:"chainID"`
-	Endpoint  string          `required:"true" yaml:"endpoint" json:"endpoint" form:"endpoint" query:"endpoint"`
-	Coin      *EVMNetworkCoin `yaml:"coin" json:"coin" form:"coin" query:"coin"`
-	TxType    int             `default:"1" yaml:"txType" json:"txType" form:"txType" query:"txType"`               // default 0, for Ethereum will be 1
-	GasLimit  int64           `default:"5000000" yaml:"gasLimit" json:"gasLimit" form:"gasLimit" query:"gasLimit"` // default 50000000 for all chains, rewrite if needed
-	GasFeeCap float64         `yaml:"gasFeeCap" json:"gasFeeCap" form:"gasFeeCap" query:"gasFeeCap"`               // parse from blockchain by default
-	GasTipCap float64         `default:"0.1" yaml:"gasTipCap" json:"gasTipCap" form:"gasTipCap" query:"gasTipCap"` // default 0.1 for Ethereum, not used on most other chains
+	ChainID              int             `required:"true" yaml:"chainID" json:"chainID" form:"chainID" query:"chainID"`
+	Endpoint             string          `required:"true" yaml:"endpoint" json:"endpoint" form:"endpoint" query:"endpoint"`
+	CheckEndpoints       []string        `yaml:"checkEndpoints" json:"checkEndpoints" form:"checkEndpoints" query:"checkEndpoints"` // independent nodes compared with endpoint, minting pauses if they disagree
+	Signer               *Signer         `yaml:"signer" json:"signer" form:"signer" query:"signer"`                                 // signer of txs on this network, the top level signer by default
+	Coin                 *EVMNetworkCoin `yaml:"coin" json:"coin" form:"coin" query:"coin"`
+	TxType               int             `default:"0" yaml:"txType" json:"txType" form:"txType" query:"txType"`                                             // 0 legacy (default), 2 EIP-1559 dynamic fee (Ethereum), 1 is refused as it meant EIP-1559 in earlier versions
+	TxEnvelope           string          `yaml:"txEnvelope" json:"txEnvelope" form:"txEnvelope" query:"txEnvelope"`                                         // legacy, accessList (EIP-2930) or dynamicFee (EIP-1559), replaces txType
+	GasLimit             int64           `default:"5000000" yaml:"gasLimit" json:"gasLimit" form:"gasLimit" query:"gasLimit"`                               // upper bound for estimated gas, default 5000000 for all chains, rewrite if needed
+	GasMultiplier        float64         `default:"1.2" yaml:"gasMultiplier" json:"gasMultiplier" form:"gasMultiplier" query:"gasMultiplier"`               // safety multiplier for estimated gas
+	GasFeeCap            float64         `yaml:"gasFeeCap" json:"gasFeeCap" form:"gasFeeCap" query:"gasFeeCap"`                                             // fixed, suggested from fee history per tx by default
+	GasTipCap            *float64        `yaml:"gasTipCap" json:"gasTipCap" form:"gasTipCap" query:"gasTipCap"`                                             // default 0.1 for Ethereum, not used on most other chains, 0 to suggest from fee history
+	Confirmations        uint64          `yaml:"confirmations" json:"confirmations" form:"confirmations" query:"confirmations"`                             // blocks to wait below the head before events are processed
+	BlockTag             string          `default:"latest" yaml:"blockTag" json:"blockTag" form:"blockTag" query:"blockTag"`                                // latest, safe or finalized
+	ReplaceAfter         *int64          `yaml:"replaceAfter" json:"replaceAfter" form:"replaceAfter" query:"replaceAfter"`                                 // seconds without receipt before a tx is replaced with bumped gas, 180 by default, 0 never replaces
+	GasBumpPercent       int64           `default:"10" yaml:"gasBumpPercent" json:"gasBumpPercent" form:"gasBumpPercent" query:"gasBumpPercent"`            // at least 10, required by nodes to accept a replacement
+	MaxGasFeeCap         float64         `yaml:"maxGasFeeCap" json:"maxGasFeeCap" form:"maxGasFeeCap" query:"maxGasFeeCap"`                                 // ceiling for suggested and bumped gas in gwei, no ceiling by default
+	MaxGasTipCap         float64         `yaml:"maxGasTipCap" json:"maxGasTipCap" form:"maxGasTipCap" query:"maxGasTipCap"`                                 // ceiling for suggested and bumped tips in gwei, no ceiling by default
+	FeeHistoryBlocks     uint64          `default:"20" yaml:"feeHistoryBlocks" json:"feeHistoryBlocks" form:"feeHistoryBlocks" query:"feeHistoryBlocks"`    // blocks of eth_feeHistory used to suggest fees
+	FeeHistoryPercentile *float64        `yaml:"feeHistoryPercentile" json:"feeHistoryPercentile" form:"feeHistoryPercentile" query:"feeHistoryPercentile"` // percentile of tips paid in those blocks, 50 by default
 
 }
 
@@ -38,10 +88,44 @@ type EVMNetworkCoin struct {
 }
 
 type Bridge struct {
-	ChainID     int    `required:"true" yaml:"chainID" json:"chainID" form:"chainID" query:"chainID"`
-	Address     string `required:"true" yaml:"address" json:"address" form:"address" query:"address"`
-	RebaseToken string `required:"true" yaml:"rebaseToken" json:"rebaseToken" form:"rebaseToken" query:"rebaseToken"`
-	BlockNumber uint64 `yaml:"blockNumber" json:"blockNumber" form:"blockNumber" query:"blockNumber"`
+	ChainID               int     `required:"true" yaml:"chainID" json:"chainID" form:"chainID" query:"chainID"`
+	Address               string  `required:"true" yaml:"address" json:"address" form:"address" query:"address"`
+	RebaseToken           string  `required:"true" yaml:"rebaseToken" json:"rebaseToken" form:"rebaseToken" query:"rebaseToken"`
+	BlockNumber           uint64  `yaml:"blockNumber" json:"blockNumber" form:"blockNumber" query:"blockNumber"`
+	Confirmations         uint64  `yaml:"confirmations" json:"confirmations" form:"confirmations" query:"confirmations"`                                      // overrides confirmations of the source network if set
+	DestinationChainID    int     `default:"619001" yaml:"destinationChainID" json:"destinationChainID" form:"destinationChainID" query:"destinationChainID"` // chain where deposits are minted, AEVM mainnet by default
+	Minter                string  `yaml:"minter" json:"minter" form:"minter" query:"minter"`                                                                  // contract called with mint on the destination chain, rebaseToken by default
+	MinterABI             string  `yaml:"minterABI" json:"minterABI" form:"minterABI" query:"minterABI"`                                                      // JSON ABI with the custom errors of the minter to decode reverts, the OpenZeppelin ERC20 errors if not set
+	Withdrawals           bool    `yaml:"withdrawals" json:"withdrawals" form:"withdrawals" query:"withdrawals"`                                              // release Redeem events of the rebase token on this bridge
+	WithdrawalBlockNumber uint64  `yaml:"withdrawalBlockNumber" json:"withdrawalBlockNumber" form:"withdrawalBlockNumber" query:"withdrawalBlockNumber"`      // destination chain block to start scanning Redeem events from
+	RedeemABI             string  `yaml:"redeemABI" json:"redeemABI" form:"redeemABI" query:"redeemABI"`                                                      // JSON ABI with the Redeem event of the rebase token, see README, the default Redeem(sender, receiver, amount, chainId) if not set
+	ReleaseABI            string  `yaml:"releaseABI" json:"releaseABI" form:"releaseABI" query:"releaseABI"`                                                  // JSON ABI with release(address,uint256) of the bridge paying out withdrawals, the default if not set
+	SourceDecimals        uint8   `yaml:"sourceDecimals" json:"sourceDecimals" form:"sourceDecimals" query:"sourceDecimals"`                                  // decimals of Deposit amounts, read from the bridge wst token if not set
+	DestinationDecimals   uint8   `yaml:"destinationDecimals" json:"destinationDecimals" form:"destinationDecimals" query:"destinationDecimals"`              // decimals of the rebase token, read from the token if not set
+	FeeFixed              string  `yaml:"feeFixed" json:"feeFixed" form:"feeFixed" query:"feeFixed"`                                                          // fixed fee per deposit in source token units
+	FeeBps                uint64  `yaml:"feeBps" json:"feeBps" form:"feeBps" query:"feeBps"`                                                                  // fee per deposit in basis points of the amount, added to the fixed fee
+	MinDeposit            string  `yaml:"minDeposit" json:"minDeposit" form:"minDeposit" query:"minDeposit"`                                                  // smaller deposits are skipped, in source token units
+	Treasury              string  `yaml:"treasury" json:"treasury" form:"treasury" query:"treasury"`                                                          // fees are minted to the treasury on the destination chain, withheld if not set
+	HourlyCap             string  `yaml:"hourlyCap" json:"hourlyCap" form:"hourlyCap" query:"hourlyCap"`                                                      // rolling 1h cap of mints, and separately of releases, in destination token units, no cap by default
+	DailyCap              string  `yaml:"dailyCap" json:"dailyCap" form:"dailyCap" query:"dailyCap"`                                                          // rolling 24h cap of mints, and separately of releases, in destination token units, no cap by default
+	ReceiverDailyCap      string  `yaml:"receiverDailyCap" json:"receiverDailyCap" form:"receiverDailyCap" query:"receiverDailyCap"`                          // rolling 24h cap of mints or releases to a single receiver in destination token units, no cap by default
+	ApprovalThreshold     string  `yaml:"approvalThreshold" json:"approvalThreshold" form:"approvalThreshold" query:"approvalThreshold"`                      // larger mints wait for operator approval, in destination token units, no approvals by default
+	ExpectedOwner         string  `yaml:"expectedOwner" json:"expectedOwner" form:"expectedOwner" query:"expectedOwner"`                                      // owner() of the bridge, not checked if not set
+	ExpectedMinter        string  `yaml:"expectedMinter" json:"expectedMinter" form:"expectedMinter" query:"expectedMinter"`                                  // minter() of the bridge, not checked if not set
+	ExpectedStakingToken  string  `yaml:"expectedStakingToken" json:"expectedStakingToken" form:"expectedStakingToken" query:"expectedStakingToken"`          // stakingToken() of the bridge, not checked if not set
+	ExpectedWst           string  `yaml:"expectedWst" json:"expectedWst" form:"expectedWst" query:"expectedWst"`                                              // wst() of the bridge, not checked if not set
+	EnforceExpected       bool    `yaml:"enforceExpected" json:"enforceExpected" form:"enforceExpected" query:"enforceExpected"`                              // pause the bridge if the on-chain values deviate from the expected ones, and until they are first read
+	Signer                *Signer `yaml:"signer" json:"signer" form:"signer" query:"signer"`                                                                  // signer of mints and releases of this bridge, the network signer by default
+	Safe                  *Safe   `yaml:"safe" json:"safe" form:"safe" query:"safe"`                                                                          // propose mints to a Safe instead of sending them, replaces attestations of the bridge
+}
+
+// Safe configures mints proposed as Safe txs, the bridge signer signs them as one of the owners
+type Safe struct {
+	Address   string `yaml:"address" json:"address" form:"address" query:"address"`         // Safe on the destination chain allowed to mint
+	MultiSend string `yaml:"multiSend" json:"multiSend" form:"multiSend" query:"multiSend"` // MultiSendCallOnly batching several mints, the canonical 1.3.0 deployment by default
+	Service   string `yaml:"service" json:"service" form:"service" query:"service"`         // base URL of a Safe transaction service receiving proposals
+	Directory string `yaml:"directory" json:"directory" form:"directory" query:"directory"` // proposals are written here for co-signers if service is not set, relative to the config dir
+	BatchSize int    `yaml:"batchSize" json:"batchSize" form:"batchSize" query:"batchSize"` // mints per Safe tx, 20 by default
 }
 
 // NewConfig creates config from configFile
@@ -89,6 +173,49 @@ func NewConfig(configPath string) (*Config, error) {
 	return config, nil
 }
 
+// GetSigner returns the top level signer
+func (c *Config) GetSigner() *Signer {
+	return &Signer{
+		PrivateKey:          c.PrivateKey,
+		Keystore:            c.Keystore,
+		PassphraseFile:      c.PassphraseFile,
+		RemoteSigner:        c.RemoteSigner,
+		RemoteSignerAddress: c.RemoteSignerAddress,
+	}
+}
+
+// GetMinter returns the contract called with mint on the destination chain
+func (b *Bridge) GetMinter() string {
+	if b.Minter != "" {
+		return b.Minter
+	}
+	return b.RebaseToken
+}
+
+// GetGasTipCap returns the fixed tip in gwei, 0 if suggested from fee history
+func (n *EVMNetwork) GetGasTipCap() float64 {
+	if n.GasTipCap != nil {
+		return *n.GasTipCap
+	}
+	return DEFAULT_GAS_TIP_CAP
+}
+
+// GetReplaceAfter returns the seconds without receipt before a tx is replaced, 0 if txs are never replaced
+func (n *EVMNetwork) GetReplaceAfter() int64 {
+	if n.ReplaceAfter != nil {
+		return *n.ReplaceAfter
+	}
+	return DEFAULT_REPLACE_AFTER
+}
+
+// GetFeeHistoryPercentile returns the percentile of tips used to suggest fees
+func (n *EVMNetwork) GetFeeHistoryPercentile() float64 {
+	if n.FeeHistoryPercentile != nil {
+		return *n.FeeHistoryPercentile
+	}
+	return DEFAULT_FEE_HISTORY_PERCENTILE
+}
+
 // getCoinByChainID finds network coin by chainID
 func (networks *EVMNetworks) GetCoinByChainID(chainID int) (*EVMNetworkCoin, error) {
 	for _, network := range *networks {
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewConfigExplicitZero(t *testing.T) {
+
+	tests := []struct {
+		name                 string
+		network              string
+		gasTipCap            float64
+		replaceAfter         int64
+		feeHistoryPercentile float64
+		gasLimit             int64
+	}{
+		{
+			name:                 "defaults",
+			network:              "",
+			gasTipCap:            DEFAULT_GAS_TIP_CAP,
+			replaceAfter:         DEFAULT_REPLACE_AFTER,
+			feeHistoryPercentile: DEFAULT_FEE_HISTORY_PERCENTILE,
+			gasLimit:             5000000,
+		},
+		{
+			name:                 "explicit zero",
+			network:              "    gasTipCap: 0\n    replaceAfter: 0\n    feeHistoryPercentile: 0\n",
+			gasTipCap:            0,
+			replaceAfter:         0,
+			feeHistoryPercentile: 0,
+			gasLimit:             5000000,
+		},
+		{
+			name:                 "explicit values",
+			network:              "    gasTipCap: 2.5\n    replaceAfter: 60\n    feeHistoryPercentile: 90\n    gasLimit: 800000\n",
+			gasTipCap:            2.5,
+			replaceAfter:         60,
+			feeHistoryPercentile: 90,
+			gasLimit:             800000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			yaml := "evmNetworks:\n  - chainID: 1\n    endpoint: http://localhost:8545\n" + tt.network
+			if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			conf, err := NewConfig(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conf.EVMNetworks) != 1 {
+				t.Fatalf("got %d networks, want 1", len(conf.EVMNetworks))
+			}
+
+			network := conf.EVMNetworks[0]
+			if got := network.GetGasTipCap(); got != tt.gasTipCap {
+				t.Errorf("gasTipCap = %v, want %v", got, tt.gasTipCap)
+			}
+			if got := network.GetReplaceAfter(); got != tt.replaceAfter {
+				t.Errorf("replaceAfter = %v, want %v", got, tt.replaceAfter)
+			}
+			if got := network.GetFeeHistoryPercentile(); got != tt.feeHistoryPercentile {
+				t.Errorf("feeHistoryPercentile = %v, want %v", got, tt.feeHistoryPercentile)
+			}
+			if network.GasLimit != tt.gasLimit {
+				t.Errorf("gasLimit = %v, want %v", network.GasLimit, tt.gasLimit)
+			}
+
+		})
+	}
+
+}
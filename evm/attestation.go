@@ -0,0 +1,104 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ATTESTED_ABI are the methods of minters and bridges verifying M-of-N relayer attestations,
+// signatures are ordered by ascending signer address
+const ATTESTED_ABI = `[{"inputs":[{"internalType":"uint256","name":"chainId","type":"uint256"},{"internalType":"address","name":"bridge","type":"address"},{"internalType":"bytes32","name":"txHash","type":"bytes32"},{"internalType":"uint256","name":"logIndex","type":"uint256"},{"internalType":"string","name":"kind","type":"string"},{"internalType":"address","name":"receiver","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"},{"internalType":"bytes[]","name":"signatures","type":"bytes[]"}],"name":"mintAttested","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"uint256","name":"chainId","type":"uint256"},{"internalType":"address","name":"bridge","type":"address"},{"internalType":"bytes32","name":"txHash","type":"bytes32"},{"internalType":"uint256","name":"logIndex","type":"uint256"},{"internalType":"string","name":"kind","type":"string"},{"internalType":"address","name":"receiver","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"},{"internalType":"bytes[]","name":"signatures","type":"bytes[]"}],"name":"releaseAttested","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// ATTESTATION_TYPE is the EIP-712 primary type signed by relayers
+const ATTESTATION_TYPE = "Attestation"
+
+// Attestation is the bridge event a relayer vouches for, chainID is the chain of the event
+type Attestation struct {
+	ChainID  int
+	Bridge   common.Address
+	TxHash   common.Hash
+	LogIndex uint
+	Kind     string // deposit, withdrawal or fee, the fee mint shares the event with its deposit
+	Receiver common.Address
+	Amount   *big.Int // executed amount in target token units
+}
+
+// TypedData returns the EIP-712 message of the attestation, verified by contract on chainID
+func (a *Attestation) TypedData(name string, version string, chainID int, contract common.Address) apitypes.TypedData {
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			ATTESTATION_TYPE: {
+				{Name: "chainId", Type: "uint256"},
+				{Name: "bridge", Type: "address"},
+				{Name: "txHash", Type: "bytes32"},
+				{Name: "logIndex", Type: "uint256"},
+				{Name: "kind", Type: "string"},
+				{Name: "receiver", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: ATTESTATION_TYPE,
+		Domain: apitypes.TypedDataDomain{
+			Name:              name,
+			Version:           version,
+			ChainId:           math.NewHexOrDecimal256(int64(chainID)),
+			VerifyingContract: contract.Hex(),
+		},
+		// numbers as decimal strings, so remote signers get the exact values
+		Message: apitypes.TypedDataMessage{
+			"chainId":  fmt.Sprint(a.ChainID),
+			"bridge":   a.Bridge.Hex(),
+			"txHash":   a.TxHash.Hex(),
+			"logIndex": fmt.Sprint(a.LogIndex),
+			"kind":     a.Kind,
+			"receiver": a.Receiver.Hex(),
+			"amount":   a.Amount.String(),
+		},
+	}
+
+}
+
+// BuildAttestedMint builds the mintAttested call of the minter
+func (e *EVMClient) BuildAttestedMint(contractAddr common.Address, a *Attestation, signatures [][]byte) (ethereum.CallMsg, error) {
+	return e.buildAttested("mintAttested", contractAddr, a, signatures)
+}
+
+// BuildAttestedRelease builds the releaseAttested call of the source chain bridge
+func (e *EVMClient) BuildAttestedRelease(bridgeAddr common.Address, a *Attestation, signatures [][]byte) (ethereum.CallMsg, error) {
+	return e.buildAttested("releaseAttested", bridgeAddr, a, signatures)
+}
+
+func (e *EVMClient) buildAttested(method string, contractAddr common.Address, a *Attestation, signatures [][]byte) (ethereum.CallMsg, error) {
+
+	attestedABI, err := abi.JSON(strings.NewReader(ATTESTED_ABI))
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	data, err := attestedABI.Pack(method, big.NewInt(int64(a.ChainID)), a.Bridge, a.TxHash, new(big.Int).SetUint64(uint64(a.LogIndex)), a.Kind, a.Receiver, a.Amount, signatures)
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+
+	return ethereum.CallMsg{
+		From:  e.PublicKey,
+		To:    &contractAddr,
+		Value: big.NewInt(0),
+		Data:  data,
+	}, nil
+
+}
@@ -0,0 +1,177 @@
+package evm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// mailTypedData is the example of the EIP-712 specification
+func mailTypedData() apitypes.TypedData {
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Person": {
+				{Name: "name", Type: "string"},
+				{Name: "wallet", Type: "address"},
+			},
+			"Mail": {
+				{Name: "from", Type: "Person"},
+				{Name: "to", Type: "Person"},
+				{Name: "contents", Type: "string"},
+			},
+		},
+		PrimaryType: "Mail",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(1),
+			VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":     map[string]interface{}{"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+			"to":       map[string]interface{}{"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+			"contents": "Hello, Bob!",
+		},
+	}
+
+}
+
+func TestTypedDataVector(t *testing.T) {
+
+	hash, _, err := apitypes.TypedDataAndHash(mailTypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hexutil.Encode(hash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; got != want {
+		t.Fatalf("digest = %s, want %s", got, want)
+	}
+
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := NewKeySigner(key).SignTypedData(mailTypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := hexutil.MustDecode("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c")
+	if !bytes.Equal(sig, want) {
+		t.Fatalf("signature = %x, want %x", sig, want)
+	}
+
+	cow := common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	tests := []struct {
+		name string
+		v    byte
+	}{
+		{"v 27", 28},
+		{"v 0", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig := common.CopyBytes(want)
+			sig[crypto.RecoveryIDOffset] = tt.v
+			from, err := RecoverTypedData(hash, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from != cow {
+				t.Errorf("recovered %s, want %s", from.Hex(), cow.Hex())
+			}
+		})
+	}
+
+}
+
+func TestAttestationDigest(t *testing.T) {
+
+	contract := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	bridge := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	receiver := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	txHash := common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444")
+
+	// the hash a minter computes on-chain, encoded by hand
+	word := func(v int64) []byte { return common.BigToHash(big.NewInt(v)).Bytes() }
+	domainSeparator := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("AEVMBridge")),
+		crypto.Keccak256([]byte("1")),
+		word(619001),
+		common.LeftPadBytes(contract.Bytes(), 32),
+	)
+	digest := func(kind string) []byte {
+		structHash := crypto.Keccak256(
+			crypto.Keccak256([]byte("Attestation(uint256 chainId,address bridge,bytes32 txHash,uint256 logIndex,string kind,address receiver,uint256 amount)")),
+			word(1),
+			common.LeftPadBytes(bridge.Bytes(), 32),
+			txHash.Bytes(),
+			word(5),
+			crypto.Keccak256([]byte(kind)),
+			common.LeftPadBytes(receiver.Bytes(), 32),
+			word(1000000),
+		)
+		return crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+	}
+
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte("relayer")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewKeySigner(key)
+
+	tests := []struct {
+		kind string
+	}{
+		{"deposit"},
+		{"fee"},
+		{"withdrawal"},
+	}
+
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+
+			a := &Attestation{ChainID: 1, Bridge: bridge, TxHash: txHash, LogIndex: 5, Kind: tt.kind, Receiver: receiver, Amount: big.NewInt(1000000)}
+			data := a.TypedData("AEVMBridge", "1", 619001, contract)
+
+			hash, _, err := apitypes.TypedDataAndHash(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := digest(tt.kind); !bytes.Equal(hash, want) {
+				t.Fatalf("digest = %x, want %x", hash, want)
+			}
+			if seen[hexutil.Encode(hash)] {
+				t.Fatalf("digest of kind %s collides", tt.kind)
+			}
+			seen[hexutil.Encode(hash)] = true
+
+			sig, err := signer.SignTypedData(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, err := RecoverTypedData(hash, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from != signer.Address() {
+				t.Errorf("recovered %s, want %s", from.Hex(), signer.Address().Hex())
+			}
+
+		})
+	}
+
+}
@@ -2,18 +2,39 @@ package evm
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/AccumulatedFinance/aevm-bridge/binding"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// ERC20_VIEW_ABI lists the ERC20 views used by the relayer
+const ERC20_VIEW_ABI = `[{"inputs":[],"name":"decimals","outputs":[{"internalType":"uint8","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"totalSupply","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
+// ErrRPCDisagreement is returned when check endpoints see a different chain than the endpoint
+var ErrRPCDisagreement = errors.New("rpc endpoints disagree")
+
+// OwnershipTransfer is an OwnershipTransferred event of the bridge
+type OwnershipTransfer struct {
+	BlockNumber   uint64
+	TxHash        string
+	PreviousOwner string
+	NewOwner      string
+}
+
 type BridgeEvent struct {
 	// event log raw
 	Timestamp   uint64 `json:"timestamp" validate:"number,gt=0"`
 	BlockNumber uint64 `json:"blockNumber" validate:"number,gt=0"`
+	BlockHash   string `json:"blockHash" validate:"required,eth_bytes32"`
 	TxHash      string `json:"txHash" validate:"required,eth_bytes32"`
+	LogIndex    uint   `json:"logIndex"`
 	// event log data
 	Receiver string   `json:"receiver" validate:"required,eth_addr"`
 	Amount   *big.Int `json:"amount" validate:"required,number,gte=0"`
@@ -48,10 +69,16 @@ func (e *EVMClient) GetDepositEvents(address string, start uint64, end *uint64)
 		if err != nil {
 			return nil, err
 		}
+		// the chain was reorganized while logs were fetched
+		if header.Hash() != iter.Event.Raw.BlockHash {
+			return nil, fmt.Errorf("block %d was reorganized during scan", blockNumber)
+		}
 		events = append(events, &BridgeEvent{
 			Timestamp:   header.Time,
 			BlockNumber: iter.Event.Raw.BlockNumber,
+			BlockHash:   iter.Event.Raw.BlockHash.Hex(),
 			TxHash:      iter.Event.Raw.TxHash.Hex(),
+			LogIndex:    iter.Event.Raw.Index,
 			Receiver:    iter.Event.Receiver.Hex(),
 			Amount:      iter.Event.Amount,
 		})
@@ -62,3 +89,162 @@ func (e *EVMClient) GetDepositEvents(address string, start uint64, end *uint64)
 
 	return events, nil
 }
+
+// GetBlockHash returns the canonical hash of the block
+func (e *EVMClient) GetBlockHash(blockNumber uint64) (string, error) {
+
+	header, err := e.Client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return "", fmt.Errorf("can not fetch block %d: %s", blockNumber, err)
+	}
+
+	return header.Hash().Hex(), nil
+}
+
+// GetBridgeWst returns the wrapped staking token of the bridge, Deposit amounts are denominated in it
+func (e *EVMClient) GetBridgeWst(address string) (common.Address, error) {
+
+	instance, err := binding.NewBridgeCaller(common.HexToAddress(address), e.Client)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return instance.Wst(&bind.CallOpts{Context: context.Background()})
+}
+
+// GetTokenDecimals returns decimals of the ERC20 token
+func (e *EVMClient) GetTokenDecimals(address common.Address) (uint8, error) {
+
+	erc20ABI, err := abi.JSON(strings.NewReader(ERC20_VIEW_ABI))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	var result []interface{}
+	token := bind.NewBoundContract(address, erc20ABI, e.Client, nil, nil)
+	if err := token.Call(&bind.CallOpts{Context: context.Background()}, &result, "decimals"); err != nil {
+		return 0, fmt.Errorf("failed to get decimals of %s: %w", address.Hex(), err)
+	}
+
+	return *abi.ConvertType(result[0], new(uint8)).(*uint8), nil
+}
+
+// VerifyBlockHash compares the hash of the block with the check endpoints, returning ErrRPCDisagreement if any of them differs.
+// Check endpoints that are behind are skipped.
+func (e *EVMClient) VerifyBlockHash(blockNumber uint64, hash string) error {
+
+	for i, client := range e.CheckClients {
+		header, err := client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(blockNumber))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("can not fetch block %d from %s: %s", blockNumber, e.CheckEndpoints[i], err)
+		}
+		if !strings.EqualFold(header.Hash().Hex(), hash) {
+			return fmt.Errorf("%w: block %d is %s on %s, %s on endpoint", ErrRPCDisagreement, blockNumber, header.Hash().Hex(), e.CheckEndpoints[i], hash)
+		}
+	}
+
+	return nil
+}
+
+// GetOwnershipTransfers returns new owners of the bridge from OwnershipTransferred events from start to end blocks
+func (e *EVMClient) GetOwnershipTransfers(address string, start uint64, end *uint64) ([]*OwnershipTransfer, error) {
+
+	var transfers []*OwnershipTransfer
+
+	instance, err := binding.NewBridgeFilterer(common.HexToAddress(address), e.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.FilterOpts{
+		Start:   start,
+		End:     end,
+		Context: context.Background(),
+	}
+
+	iter, err := instance.FilterOwnershipTransferred(opts, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for iter.Next() {
+		transfers = append(transfers, &OwnershipTransfer{
+			BlockNumber:   iter.Event.Raw.BlockNumber,
+			TxHash:        iter.Event.Raw.TxHash.Hex(),
+			PreviousOwner: iter.Event.PreviousOwner.Hex(),
+			NewOwner:      iter.Event.NewOwner.Hex(),
+		})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// BridgeConfig is the on-chain configuration of the bridge
+type BridgeConfig struct {
+	Owner        string
+	Minter       string
+	StakingToken string
+	Wst          string
+}
+
+// GetBridgeConfig reads the on-chain configuration of the bridge
+func (e *EVMClient) GetBridgeConfig(address string) (*BridgeConfig, error) {
+
+	instance, err := binding.NewBridgeCaller(common.HexToAddress(address), e.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.CallOpts{Context: context.Background()}
+
+	owner, err := instance.Owner(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owner: %w", err)
+	}
+
+	minter, err := instance.Minter(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minter: %w", err)
+	}
+
+	stakingToken, err := instance.StakingToken(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staking token: %w", err)
+	}
+
+	wst, err := instance.Wst(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wst: %w", err)
+	}
+
+	return &BridgeConfig{
+		Owner:        owner.Hex(),
+		Minter:       minter.Hex(),
+		StakingToken: stakingToken.Hex(),
+		Wst:          wst.Hex(),
+	}, nil
+}
+
+// GetTokenTotalSupply returns totalSupply of the ERC20 token
+func (e *EVMClient) GetTokenTotalSupply(address common.Address) (*big.Int, error) {
+
+	erc20ABI, err := abi.JSON(strings.NewReader(ERC20_VIEW_ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	var result []interface{}
+	token := bind.NewBoundContract(address, erc20ABI, e.Client, nil, nil)
+	if err := token.Call(&bind.CallOpts{Context: context.Background()}, &result, "totalSupply"); err != nil {
+		return nil, fmt.Errorf("failed to get total supply of %s: %w", address.Hex(), err)
+	}
+
+	return *abi.ConvertType(result[0], new(*big.Int)).(**big.Int), nil
+}
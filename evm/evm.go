@@ -11,25 +11,59 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const GWEI = 1e9
 
 const EVM_CLIENT_DELAY = 100 * time.Millisecond
 const EVM_EVENTS_LIMIT = 29
+const EVM_TX_REBROADCAST_TIMEOUT = 2 * time.Minute
+const EVM_MIN_GAS_BUMP_PERCENT = 10
 const ETH_ZERO_ADDRESS = "0x0000000000000000000000000000000000000000"
 const ETH_ETHER_ADDRESS = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
 const ETH_ETHER_ADDRESS_MIXED = "0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE"
 
+const TX_TYPE_LEGACY = 0
+const TX_TYPE_ACCESS_LIST = 1
+const TX_TYPE_DYNAMIC_FEE = 2
+
+// TX_ENVELOPES are the tx types by txEnvelope name
+var TX_ENVELOPES = map[string]int{
+	"legacy":     TX_TYPE_LEGACY,
+	"accessList": TX_TYPE_ACCESS_LIST,
+	"dynamicFee": TX_TYPE_DYNAMIC_FEE,
+}
+
+const BLOCK_TAG_LATEST = "latest"
+const BLOCK_TAG_SAFE = "safe"
+const BLOCK_TAG_FINALIZED = "finalized"
+
 type EVMClient struct {
-	ChainID    int               `json:"chainId" validate:"number,gt=0"`
-	TxType     int               // 0 or 1
-	Client     *ethclient.Client `json:"client"`
-	PrivateKey *ecdsa.PrivateKey
-	PublicKey  common.Address
-	GasFeeCap  *big.Int
-	GasTipCap  *big.Int
-	GasLimit   uint64
+	ChainID   int               `json:"chainId" validate:"number,gt=0"`
+	TxType    int               // 0 legacy, 1 EIP-2930 access list, 2 EIP-1559 dynamic fee
+	Client    *ethclient.Client `json:"client"`
+	Signer    Signer
+	PublicKey common.Address // account of the signer
+	GasFeeCap *big.Int       // fixed, zero if suggested by the fee oracle
+	GasTipCap *big.Int       // fixed, zero if suggested by the fee oracle
+	GasLimit  uint64         // upper bound for estimated gas
+	// gas estimation
+	GasMultiplier float64
+	// fee oracle
+	FeeHistoryBlocks     uint64
+	FeeHistoryPercentile float64
+	MaxGasTipCap         *big.Int
+	// finality of scanned blocks
+	Confirmations uint64
+	BlockTag      string
+	// replacement of stuck txs
+	ReplaceAfter   time.Duration
+	GasBumpPercent int64
+	MaxGasFeeCap   *big.Int // ceiling for suggested and bumped gas
+	// independent nodes cross-checking the endpoint
+	CheckEndpoints []string
+	CheckClients   []*ethclient.Client
 }
 
 // NewEVMClient constructs the EVM client
@@ -45,41 +79,87 @@ func NewEVMClient(conf config.EVMNetwork) (*EVMClient, error) {
 
 	c.Client = client
 
+	for _, endpoint := range conf.CheckEndpoints {
+		checkClient, err := ethclient.Dial(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("can not connect to node: %s", endpoint)
+		}
+		c.CheckEndpoints = append(c.CheckEndpoints, endpoint)
+		c.CheckClients = append(c.CheckClients, checkClient)
+	}
+
+	// load tx type from config
+	if c.TxType, err = loadTxType(conf); err != nil {
+		return nil, err
+	}
+
+	// load finality params from config
+	switch conf.BlockTag {
+	case BLOCK_TAG_LATEST, BLOCK_TAG_SAFE, BLOCK_TAG_FINALIZED:
+		c.BlockTag = conf.BlockTag
+	default:
+		return nil, fmt.Errorf("unsupported block tag %q for chainID %d", conf.BlockTag, conf.ChainID)
+	}
+	c.Confirmations = conf.Confirmations
+
 	// load gas params from config
 	c.GasLimit = uint64(conf.GasLimit)
+	if conf.GasMultiplier < 1 {
+		return nil, fmt.Errorf("gasMultiplier for chainID %d must be at least 1", conf.ChainID)
+	}
+	c.GasMultiplier = conf.GasMultiplier
 
-	gasFeeCap := big.NewFloat(conf.GasFeeCap)
-	gasFeeCap.Mul(gasFeeCap, big.NewFloat(GWEI))
-	c.GasFeeCap, _ = gasFeeCap.Int(nil)
+	// fixed gas params, suggested by the fee oracle per tx if not set
+	c.GasFeeCap = gweiToWei(conf.GasFeeCap)
+	c.GasTipCap = gweiToWei(conf.GetGasTipCap())
 
-	// if no GasFeeCap set, parse from blockchain
-	if c.GasFeeCap.Cmp(big.NewInt(0)) == 0 {
-		gasPrice, err := client.SuggestGasPrice(context.Background())
-		if err != nil {
-			return nil, err
-		}
-		c.GasFeeCap = gasPrice
+	// load fee oracle params from config
+	if conf.GetFeeHistoryPercentile() < 0 || conf.GetFeeHistoryPercentile() > 100 {
+		return nil, fmt.Errorf("feeHistoryPercentile for chainID %d must be between 0 and 100", conf.ChainID)
 	}
+	c.FeeHistoryBlocks = conf.FeeHistoryBlocks
+	c.FeeHistoryPercentile = conf.GetFeeHistoryPercentile()
+	c.MaxGasTipCap = gweiToWei(conf.MaxGasTipCap)
 
-	// tx type 1
-	if c.TxType == 1 {
-		gasTipCap := big.NewFloat(conf.GasTipCap)
-		gasTipCap.Mul(gasTipCap, big.NewFloat(GWEI))
-		c.GasTipCap, _ = gasTipCap.Int(nil)
-		// if no GasTipCap set, parse from blockchain
-		if c.GasTipCap.Cmp(big.NewInt(0)) == 0 {
-			gasTip, err := client.SuggestGasTipCap(context.Background())
-			if err != nil {
-				return nil, err
-			}
-			c.GasTipCap = gasTip
-		}
+	// load replacement params from config
+	if conf.GasBumpPercent < EVM_MIN_GAS_BUMP_PERCENT {
+		return nil, fmt.Errorf("gasBumpPercent for chainID %d must be at least %d", conf.ChainID, EVM_MIN_GAS_BUMP_PERCENT)
 	}
+	c.GasBumpPercent = conf.GasBumpPercent
+	if conf.GetReplaceAfter() < 0 {
+		return nil, fmt.Errorf("replaceAfter for chainID %d must not be negative", conf.ChainID)
+	}
+	c.ReplaceAfter = time.Duration(conf.GetReplaceAfter()) * time.Second
+
+	c.MaxGasFeeCap = gweiToWei(conf.MaxGasFeeCap)
 
 	return c, nil
 
 }
 
+// loadTxType returns the tx type of the network, txType 1 meant EIP-1559 in earlier versions so the access list envelope must be named
+func loadTxType(conf config.EVMNetwork) (int, error) {
+
+	switch {
+	case conf.TxEnvelope != "":
+		txType, ok := TX_ENVELOPES[conf.TxEnvelope]
+		if !ok {
+			return 0, fmt.Errorf("unsupported txEnvelope %q for chainID %d", conf.TxEnvelope, conf.ChainID)
+		}
+		if conf.TxType != TX_TYPE_LEGACY && conf.TxType != txType {
+			return 0, fmt.Errorf("txType %d and txEnvelope %q disagree for chainID %d", conf.TxType, conf.TxEnvelope, conf.ChainID)
+		}
+		return txType, nil
+	case conf.TxType == TX_TYPE_ACCESS_LIST:
+		return 0, fmt.Errorf("txType 1 for chainID %d is ambiguous, it meant EIP-1559 in earlier versions: set txType 2 for EIP-1559 or txEnvelope accessList for EIP-2930", conf.ChainID)
+	case conf.TxType == TX_TYPE_LEGACY, conf.TxType == TX_TYPE_DYNAMIC_FEE:
+		return conf.TxType, nil
+	default:
+		return 0, fmt.Errorf("unsupported txType %d for chainID %d", conf.TxType, conf.ChainID)
+	}
+
+}
+
 // GetCurrentBlockNumber returns the current block number of the connected Ethereum chain.
 func (e *EVMClient) GetCurrentBlockNumber() (uint64, error) {
 	// Fetch the latest block number using the Ethereum client
@@ -90,6 +170,32 @@ func (e *EVMClient) GetCurrentBlockNumber() (uint64, error) {
 	return blockNumber, nil
 }
 
+// GetConfirmedBlockNumber returns the highest block that is final enough to process:
+// the block of the configured tag minus the confirmation depth
+func (e *EVMClient) GetConfirmedBlockNumber(confirmations uint64) (uint64, error) {
+
+	var tag *big.Int
+	switch e.BlockTag {
+	case BLOCK_TAG_SAFE:
+		tag = big.NewInt(int64(rpc.SafeBlockNumber))
+	case BLOCK_TAG_FINALIZED:
+		tag = big.NewInt(int64(rpc.FinalizedBlockNumber))
+	}
+
+	// nil tag fetches the latest block
+	header, err := e.Client.HeaderByNumber(context.Background(), tag)
+	if err != nil {
+		return 0, fmt.Errorf("can not fetch %s block: %s", e.BlockTag, err)
+	}
+
+	blockNumber := header.Number.Uint64()
+	if blockNumber < confirmations {
+		return 0, nil
+	}
+
+	return blockNumber - confirmations, nil
+}
+
 // ImportPrivateKey imports private key and generates corresponding public key
 func (e *EVMClient) ImportPrivateKey(pk string) (*EVMClient, error) {
 
@@ -98,16 +204,27 @@ func (e *EVMClient) ImportPrivateKey(pk string) (*EVMClient, error) {
 		return nil, err
 	}
 
-	e.PrivateKey = privateKey
+	return e.ImportKey(privateKey), nil
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("error casting public key to ECDSA")
-	}
+}
 
-	e.PublicKey = crypto.PubkeyToAddress(*publicKeyECDSA)
+// ImportKey signs with the private key held in memory
+func (e *EVMClient) ImportKey(privateKey *ecdsa.PrivateKey) *EVMClient {
+	return e.SetSigner(NewKeySigner(privateKey))
+}
+
+// SetSigner sets the signer of txs sent by the client
+func (e *EVMClient) SetSigner(signer Signer) *EVMClient {
+
+	e.Signer = signer
+	e.PublicKey = signer.Address()
 
-	return e, nil
+	return e
+
+}
 
+// WithSigner returns a copy of the client sending txs with another signer over the same connections
+func (e *EVMClient) WithSigner(signer Signer) *EVMClient {
+	c := *e
+	return c.SetSigner(signer)
 }
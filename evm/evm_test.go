@@ -0,0 +1,42 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+)
+
+func TestLoadTxType(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		txType   int
+		envelope string
+		want     int
+		wantErr  bool
+	}{
+		{name: "default legacy", want: TX_TYPE_LEGACY},
+		{name: "dynamic fee", txType: 2, want: TX_TYPE_DYNAMIC_FEE},
+		{name: "ambiguous 1", txType: 1, wantErr: true},
+		{name: "unsupported", txType: 3, wantErr: true},
+		{name: "access list envelope", envelope: "accessList", want: TX_TYPE_ACCESS_LIST},
+		{name: "dynamic fee envelope", envelope: "dynamicFee", want: TX_TYPE_DYNAMIC_FEE},
+		{name: "legacy envelope", envelope: "legacy", want: TX_TYPE_LEGACY},
+		{name: "envelope matching txType", txType: 1, envelope: "accessList", want: TX_TYPE_ACCESS_LIST},
+		{name: "envelope contradicting txType", txType: 2, envelope: "accessList", wantErr: true},
+		{name: "unknown envelope", envelope: "blob", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadTxType(config.EVMNetwork{ChainID: 1, TxType: tt.txType, TxEnvelope: tt.envelope})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("txType = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,109 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Fees are gas params of a single tx
+type Fees struct {
+	GasPrice  *big.Int // legacy and access list txs
+	GasFeeCap *big.Int // dynamic fee txs
+	GasTipCap *big.Int // dynamic fee txs
+}
+
+// SuggestFees returns gas params for the next tx.
+// Fixed values from config win, the rest is derived from eth_feeHistory: the tip is the median of the configured
+// reward percentile, the fee cap covers two doublings of the next base fee and the gas price pays the next base fee and tip.
+// All of them are limited by the configured caps.
+func (e *EVMClient) SuggestFees() (*Fees, error) {
+
+	fees := &Fees{
+		GasPrice:  new(big.Int).Set(e.GasFeeCap),
+		GasFeeCap: new(big.Int).Set(e.GasFeeCap),
+		GasTipCap: new(big.Int).Set(e.GasTipCap),
+	}
+
+	if fees.GasFeeCap.Sign() == 0 || fees.GasTipCap.Sign() == 0 {
+		baseFee, tip, err := e.feeHistory()
+		if err != nil {
+			return nil, err
+		}
+		if fees.GasTipCap.Sign() == 0 {
+			fees.GasTipCap = tip
+		}
+		if fees.GasFeeCap.Sign() == 0 {
+			fees.GasFeeCap = new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), fees.GasTipCap)
+			fees.GasPrice = new(big.Int).Add(baseFee, fees.GasTipCap)
+		}
+	}
+
+	if e.MaxGasTipCap.Sign() > 0 && fees.GasTipCap.Cmp(e.MaxGasTipCap) > 0 {
+		fees.GasTipCap = new(big.Int).Set(e.MaxGasTipCap)
+	}
+	if e.MaxGasFeeCap.Sign() > 0 && fees.GasFeeCap.Cmp(e.MaxGasFeeCap) > 0 {
+		log.WithField("prefix", "evm").Warn("Suggested gas fee cap ", fees.GasFeeCap, " on chain ", e.ChainID, " limited to ", e.MaxGasFeeCap)
+		fees.GasFeeCap = new(big.Int).Set(e.MaxGasFeeCap)
+	}
+	if e.MaxGasFeeCap.Sign() > 0 && fees.GasPrice.Cmp(e.MaxGasFeeCap) > 0 {
+		log.WithField("prefix", "evm").Warn("Suggested gas price ", fees.GasPrice, " on chain ", e.ChainID, " limited to ", e.MaxGasFeeCap)
+		fees.GasPrice = new(big.Int).Set(e.MaxGasFeeCap)
+	}
+	// the tip can not be higher than the fee cap
+	if fees.GasTipCap.Cmp(fees.GasFeeCap) > 0 {
+		fees.GasTipCap = new(big.Int).Set(fees.GasFeeCap)
+	}
+
+	return fees, nil
+
+}
+
+// feeHistory returns the base fee of the next block and the median tip of recent blocks.
+// Chains without eth_feeHistory or base fee fall back to the node suggestions.
+func (e *EVMClient) feeHistory() (*big.Int, *big.Int, error) {
+
+	history, err := e.Client.FeeHistory(context.Background(), e.FeeHistoryBlocks, nil, []float64{e.FeeHistoryPercentile})
+	if err != nil || len(history.BaseFee) == 0 || history.BaseFee[len(history.BaseFee)-1].Sign() == 0 {
+		log.WithField("prefix", "evm").Debug("Fee history unavailable on chain ", e.ChainID, ", using node suggestions: ", err)
+		return e.suggestedFees()
+	}
+
+	// the last base fee is the one of the next block
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	var tips []*big.Int
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			tips = append(tips, reward[0])
+		}
+	}
+
+	if len(tips) == 0 {
+		tip, err := e.Client.SuggestGasTipCap(context.Background())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to suggest gas tip: %w", err)
+		}
+		return baseFee, tip, nil
+	}
+
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+
+	return baseFee, tips[len(tips)/2], nil
+
+}
+
+// suggestedFees returns the node gas price as base fee with zero tip
+func (e *EVMClient) suggestedFees() (*big.Int, *big.Int, error) {
+
+	gasPrice, err := e.Client.SuggestGasPrice(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to suggest gas price: %w", err)
+	}
+
+	return gasPrice, big.NewInt(0), nil
+
+}
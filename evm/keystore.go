@@ -0,0 +1,38 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// LoadKeystore decrypts the private key from a V3 JSON keystore file
+func LoadKeystore(file string, passphrase string) (*ecdsa.PrivateKey, error) {
+
+	keyJSON, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("can not read keystore: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("can not decrypt keystore %s: %w", file, err)
+	}
+
+	return key.PrivateKey, nil
+
+}
+
+// NewKeystoreSigner creates a signer of the key in a V3 JSON keystore file
+func NewKeystoreSigner(file string, passphrase string) (*KeySigner, error) {
+
+	key, err := LoadKeystore(file, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeySigner(key), nil
+
+}
@@ -0,0 +1,176 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	log "github.com/sirupsen/logrus"
+)
+
+const EVM_NONCE_RESYNC_INTERVAL = 1 * time.Minute
+const EVM_NONCE_STUCK_TIMEOUT = 10 * time.Minute
+
+type nonceKey struct {
+	ChainID int
+	Address common.Address
+}
+
+var nonceLock = &sync.Mutex{}
+var nonceManagers = make(map[nonceKey]*NonceManager)
+
+// NonceManager allocates nonces of a single signer on a single chain serially
+type NonceManager struct {
+	mu       sync.Mutex
+	client   *ethclient.Client
+	chainID  int
+	address  common.Address
+	floor    uint64               // lowest nonce not known to the node
+	latest   uint64               // lowest nonce not mined yet
+	inflight map[uint64]time.Time // allocated nonces not mined yet
+	syncedAt time.Time
+}
+
+// Nonces returns the nonce manager of the client signer, shared by all users of the same chain and key
+func (e *EVMClient) Nonces() *NonceManager {
+
+	nonceLock.Lock()
+	defer nonceLock.Unlock()
+
+	key := nonceKey{e.ChainID, e.PublicKey}
+	n, exists := nonceManagers[key]
+	if !exists {
+		n = &NonceManager{
+			client:   e.Client,
+			chainID:  e.ChainID,
+			address:  e.PublicKey,
+			inflight: make(map[uint64]time.Time),
+		}
+		nonceManagers[key] = n
+	}
+
+	return n
+}
+
+// Acquire allocates the lowest free nonce, filling gaps left by released nonces first
+func (n *NonceManager) Acquire() (uint64, error) {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if time.Since(n.syncedAt) > EVM_NONCE_RESYNC_INTERVAL {
+		if err := n.resync(); err != nil {
+			return 0, err
+		}
+	}
+
+	nonce := n.next()
+	n.inflight[nonce] = time.Now()
+
+	return nonce, nil
+}
+
+// Release returns a nonce that was never broadcast, so it is allocated again
+func (n *NonceManager) Release(nonce uint64) {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.inflight, nonce)
+}
+
+// Restore marks a nonce of a tx submitted before restart as in-flight
+func (n *NonceManager) Restore(nonce uint64, since time.Time) {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.inflight[nonce] = since
+}
+
+// Invalidate forces a resync with the node before the next allocation
+func (n *NonceManager) Invalidate() {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.syncedAt = time.Time{}
+}
+
+// Stuck resyncs with the node and returns the lowest in-flight nonce if it has not been mined for longer than timeout,
+// every later tx of the signer waits for it
+func (n *NonceManager) Stuck(timeout time.Duration) (uint64, bool, error) {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.resync(); err != nil {
+		return 0, false, err
+	}
+
+	since, exists := n.inflight[n.latest]
+	if !exists || time.Since(since) < timeout {
+		return 0, false, nil
+	}
+
+	return n.latest, true, nil
+}
+
+// resync reloads nonces from the node, the caller must hold the lock
+func (n *NonceManager) resync() error {
+
+	latest, err := n.client.NonceAt(context.Background(), n.address, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	pending, err := n.client.PendingNonceAt(context.Background(), n.address)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	// mined nonces are no longer in-flight
+	for nonce := range n.inflight {
+		if nonce < latest {
+			delete(n.inflight, nonce)
+		}
+	}
+
+	// in-flight nonces unknown to the node leave a gap, they must be rebroadcast before later txs are mined
+	var gaps []uint64
+	for nonce := range n.inflight {
+		if nonce >= pending {
+			gaps = append(gaps, nonce)
+		}
+	}
+	if len(gaps) > 0 {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		log.WithField("prefix", "evm").Warn("In-flight nonces ", gaps, " of ", n.address.Hex(), " on chain ", n.chainID, " unknown to the node, pending nonce is ", pending)
+	}
+
+	if next := n.next(); pending > next && !n.syncedAt.IsZero() {
+		log.WithField("prefix", "evm").Warn("Nonce of ", n.address.Hex(), " on chain ", n.chainID, " moved from ", next, " to ", pending, " outside of the relayer")
+	}
+
+	n.latest = latest
+	n.floor = pending
+	n.syncedAt = time.Now()
+
+	return nil
+}
+
+// next returns the lowest nonce that is neither known to the node nor in-flight, the caller must hold the lock
+func (n *NonceManager) next() uint64 {
+
+	nonce := n.floor
+	for {
+		if _, exists := n.inflight[nonce]; !exists {
+			return nonce
+		}
+		nonce++
+	}
+}
@@ -0,0 +1,49 @@
+package evm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNonceAllocation(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		floor    uint64   // pending nonce of the node
+		inflight []uint64 // allocated before
+		released []uint64 // never broadcast
+		want     []uint64 // next allocations
+	}{
+		{name: "from the pending nonce", floor: 5, want: []uint64{5, 6, 7}},
+		{name: "after in-flight nonces", floor: 5, inflight: []uint64{5, 6}, want: []uint64{7, 8}},
+		{name: "released nonce first", floor: 5, inflight: []uint64{5, 6, 7}, released: []uint64{6}, want: []uint64{6, 8}},
+		{name: "gaps in order", floor: 5, inflight: []uint64{5, 6, 7, 8}, released: []uint64{7, 5}, want: []uint64{5, 7, 9}},
+		{name: "restored nonce skipped", floor: 5, inflight: []uint64{6}, want: []uint64{5, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// synced just now, Acquire does not ask the node
+			n := &NonceManager{floor: tt.floor, inflight: make(map[uint64]time.Time), syncedAt: time.Now()}
+			for _, nonce := range tt.inflight {
+				n.Restore(nonce, time.Now())
+			}
+			for _, nonce := range tt.released {
+				n.Release(nonce)
+			}
+
+			for _, want := range tt.want {
+				got, err := n.Acquire()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Fatalf("Acquire = %d, want %d", got, want)
+				}
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,189 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const EVM_REMOTE_SIGNER_TIMEOUT = 30 * time.Second
+
+// EVM_RPC_METHOD_NOT_FOUND is the JSON-RPC error code of unknown methods
+const EVM_RPC_METHOD_NOT_FOUND = -32601
+
+// RemoteSigner signs with eth_signTransaction and typed data methods of an external signer such as Clef or web3signer, the key never enters the relayer
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// signTxArgs are the eth_signTransaction params understood by Clef and web3signer
+type signTxArgs struct {
+	From                 common.Address    `json:"from"`
+	To                   *common.Address   `json:"to,omitempty"`
+	Gas                  hexutil.Uint64    `json:"gas"`
+	GasPrice             *hexutil.Big      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big      `json:"value"`
+	Nonce                hexutil.Uint64    `json:"nonce"`
+	Data                 hexutil.Bytes     `json:"data"`
+	ChainID              *hexutil.Big      `json:"chainId"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+}
+
+// NewRemoteSigner connects to the signer endpoint, http, ws or ipc
+func NewRemoteSigner(endpoint string, address string) (*RemoteSigner, error) {
+
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid remote signer address %s", address)
+	}
+
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to remote signer %s: %w", endpoint, err)
+	}
+
+	return &RemoteSigner{
+		client:  client,
+		address: common.HexToAddress(address),
+	}, nil
+
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx asks the remote signer to sign tx and verifies that it signed exactly tx with the expected account
+func (s *RemoteSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+
+	args := &signTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.AccessListTxType:
+		accessList := tx.AccessList()
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+		args.AccessList = &accessList
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("remote signer does not support tx type %d", tx.Type())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), EVM_REMOTE_SIGNER_TIMEOUT)
+	defer cancel()
+
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+
+	raw, err := decodeSignResult(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid tx: %w", err)
+	}
+
+	// the signing hash covers every field, so any change made by the signer is caught
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, fmt.Errorf("remote signer returned a different tx")
+	}
+
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid signature: %w", err)
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s instead of %s", from.Hex(), s.address.Hex())
+	}
+
+	return signed, nil
+
+}
+
+// SignTypedData asks the remote signer for the EIP-712 signature of data and verifies the signing account
+func (s *RemoteSigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), EVM_REMOTE_SIGNER_TIMEOUT)
+	defer cancel()
+
+	// Clef signs typed data with account_signTypedData, web3signer with eth_signTypedData
+	var sig hexutil.Bytes
+	err = s.client.CallContext(ctx, &sig, "account_signTypedData", s.address, data)
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == EVM_RPC_METHOD_NOT_FOUND {
+		err = s.client.CallContext(ctx, &sig, "eth_signTypedData", s.address, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("remote signer returned invalid signature %s", sig)
+	}
+	if sig[crypto.RecoveryIDOffset] < 27 {
+		sig[crypto.RecoveryIDOffset] += 27
+	}
+
+	// a signature over anything else recovers to another account
+	from, err := RecoverTypedData(hash, sig)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid signature: %w", err)
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s instead of %s", from.Hex(), s.address.Hex())
+	}
+
+	return sig, nil
+
+}
+
+// decodeSignResult accepts the raw tx of web3signer and the {raw, tx} object of Clef
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+
+	var clef struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &clef); err != nil || len(clef.Raw) == 0 {
+		return nil, fmt.Errorf("remote signer returned unexpected result %s", string(result))
+	}
+
+	return clef.Raw, nil
+
+}
@@ -0,0 +1,226 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// rpcRequest is the JSON-RPC request received by the signer stand-in
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// rpcError is a JSON-RPC error returned by the signer stand-in
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newSignerServer serves JSON-RPC, respond returns the result of a request
+func newSignerServer(t *testing.T, respond func(req rpcRequest) interface{}) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		result := respond(req)
+		if rpcErr, ok := result.(*rpcError); ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+
+}
+
+func mustKey(t *testing.T, hex string) *ecdsa.PrivateKey {
+	key, err := crypto.HexToECDSA(hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestRemoteSignerSignTx(t *testing.T) {
+
+	key := mustKey(t, "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	otherKey := mustKey(t, "8da4ef21b864d2cc526dbfb2a8a6f2fa2f16ec4d2e6c9b1c5c3e2a1b0a9f8e7d")
+	chainID := big.NewInt(619001)
+	signer := types.LatestSignerForChainID(chainID)
+
+	to := common.HexToAddress("0x000000000000000000000000000000000000bEEF")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(3e9),
+		Gas:       100000,
+		To:        &to,
+		Value:     big.NewInt(0),
+		Data:      []byte{0x40, 0xc1, 0x0f, 0x19},
+	})
+	tampered := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(3e9),
+		Gas:       100000,
+		To:        &to,
+		Value:     big.NewInt(1),
+		Data:      []byte{0x40, 0xc1, 0x0f, 0x19},
+	})
+
+	sign := func(tx *types.Transaction, key *ecdsa.PrivateKey) hexutil.Bytes {
+		signed, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := signed.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name    string
+		result  interface{}
+		wantErr string
+	}{
+		{name: "raw tx", result: sign(tx, key)},
+		{name: "clef raw and tx", result: map[string]interface{}{"raw": sign(tx, key), "tx": map[string]string{"nonce": "0x7"}}},
+		{name: "tampered tx", result: sign(tampered, key), wantErr: "different tx"},
+		{name: "wrong signer", result: sign(tx, otherKey), wantErr: "signed with"},
+		{name: "unexpected result", result: map[string]string{"tx": "0x"}, wantErr: "unexpected result"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			server := newSignerServer(t, func(req rpcRequest) interface{} {
+				if req.Method != "eth_signTransaction" {
+					t.Errorf("method = %s, want eth_signTransaction", req.Method)
+				}
+				return tt.result
+			})
+			defer server.Close()
+
+			remote, err := NewRemoteSigner(server.URL, crypto.PubkeyToAddress(key.PublicKey).Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signed, err := remote.SignTx(tx, chainID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.Hash(signed) != signer.Hash(tx) {
+				t.Errorf("signed tx differs from the requested one")
+			}
+
+		})
+	}
+
+}
+
+func TestNewRemoteSignerWrapsDialError(t *testing.T) {
+
+	_, err := NewRemoteSigner("unsupported://signer", "0x000000000000000000000000000000000000bEEF")
+	if err == nil || errors.Unwrap(err) == nil {
+		t.Fatalf("err = %v, want the wrapped rpc.Dial error", err)
+	}
+
+}
+
+func TestRemoteSignerSignTypedData(t *testing.T) {
+
+	key := mustKey(t, "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	otherKey := mustKey(t, "8da4ef21b864d2cc526dbfb2a8a6f2fa2f16ec4d2e6c9b1c5c3e2a1b0a9f8e7d")
+
+	data := (&Attestation{
+		ChainID:  1,
+		Bridge:   common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		TxHash:   common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444"),
+		LogIndex: 5,
+		Kind:     "deposit",
+		Receiver: common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		Amount:   big.NewInt(1000000),
+	}).TypedData("AEVMBridge", "1", 619001, common.HexToAddress("0x1111111111111111111111111111111111111111"))
+
+	sign := func(key *ecdsa.PrivateKey, v byte) hexutil.Bytes {
+		sig, err := NewKeySigner(key).SignTypedData(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig[crypto.RecoveryIDOffset] -= 27 - v
+		return sig
+	}
+
+	tests := []struct {
+		name    string
+		methods map[string]interface{} // results by method, unknown methods are not found
+		wantErr string
+	}{
+		{name: "clef", methods: map[string]interface{}{"account_signTypedData": sign(key, 27)}},
+		{name: "clef v 0", methods: map[string]interface{}{"account_signTypedData": sign(key, 0)}},
+		{name: "web3signer", methods: map[string]interface{}{"eth_signTypedData": sign(key, 27)}},
+		{name: "wrong signer", methods: map[string]interface{}{"account_signTypedData": sign(otherKey, 27)}, wantErr: "signed with"},
+		{name: "denied", methods: map[string]interface{}{"account_signTypedData": &rpcError{Code: -32000, Message: "Request denied"}}, wantErr: "Request denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			server := newSignerServer(t, func(req rpcRequest) interface{} {
+				if result, ok := tt.methods[req.Method]; ok {
+					return result
+				}
+				return &rpcError{Code: EVM_RPC_METHOD_NOT_FOUND, Message: "the method " + req.Method + " does not exist/is not available"}
+			})
+			defer server.Close()
+
+			remote, err := NewRemoteSigner(server.URL, crypto.PubkeyToAddress(key.PublicKey).Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sig, err := remote.SignTypedData(data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig[crypto.RecoveryIDOffset] < 27 {
+				t.Errorf("v = %d, want 27 or 28", sig[crypto.RecoveryIDOffset])
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,107 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrGasCeiling is returned when a replacement would need gas above the configured ceiling
+var ErrGasCeiling = errors.New("replacement gas exceeds the configured ceiling")
+
+// BumpTx re-signs tx with the same nonce and gas fees bumped by at least GasBumpPercent,
+// but not less than the currently suggested fees
+func (e *EVMClient) BumpTx(tx *types.Transaction) (*types.Transaction, error) {
+
+	fees, err := e.SuggestFees()
+	if err != nil {
+		return nil, err
+	}
+
+	var data types.TxData
+
+	switch tx.Type() {
+	case types.LegacyTxType:
+		gasPrice, err := e.bumpGas(tx.GasPrice(), fees.GasPrice)
+		if err != nil {
+			return nil, err
+		}
+		data = &types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}
+	case types.AccessListTxType:
+		gasPrice, err := e.bumpGas(tx.GasPrice(), fees.GasPrice)
+		if err != nil {
+			return nil, err
+		}
+		data = &types.AccessListTx{
+			ChainID:    big.NewInt(int64(e.ChainID)),
+			Nonce:      tx.Nonce(),
+			GasPrice:   gasPrice,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}
+	case types.DynamicFeeTxType:
+		gasFeeCap, err := e.bumpGas(tx.GasFeeCap(), fees.GasFeeCap)
+		if err != nil {
+			return nil, err
+		}
+		gasTipCap, err := e.bumpGas(tx.GasTipCap(), fees.GasTipCap)
+		if err != nil {
+			return nil, err
+		}
+		// the tip has its own ceiling, like suggested tips
+		if e.MaxGasTipCap.Sign() > 0 && gasTipCap.Cmp(e.MaxGasTipCap) > 0 {
+			gasTipCap = new(big.Int).Set(e.MaxGasTipCap)
+		}
+		// the tip can not be higher than the fee cap
+		if gasTipCap.Cmp(gasFeeCap) > 0 {
+			gasTipCap = new(big.Int).Set(gasFeeCap)
+		}
+		data = &types.DynamicFeeTx{
+			ChainID:   big.NewInt(int64(e.ChainID)),
+			Nonce:     tx.Nonce(),
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
+			Gas:       tx.Gas(),
+			To:        tx.To(),
+			Value:     tx.Value(),
+			Data:      tx.Data(),
+		}
+	default:
+		return nil, fmt.Errorf("can not replace tx of type %d", tx.Type())
+	}
+
+	return e.SignTx(types.NewTx(data))
+
+}
+
+// bumpGas returns the higher of the bumped previous value and the current value, failing above the ceiling
+func (e *EVMClient) bumpGas(previous *big.Int, current *big.Int) (*big.Int, error) {
+
+	// round up, nodes reject replacements bumped by less than the required percent
+	bumped := new(big.Int).Mul(previous, big.NewInt(100+e.GasBumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+
+	if current != nil && current.Cmp(bumped) > 0 {
+		bumped = new(big.Int).Set(current)
+	}
+
+	if e.MaxGasFeeCap.Sign() > 0 && bumped.Cmp(e.MaxGasFeeCap) > 0 {
+		return nil, ErrGasCeiling
+	}
+
+	return bumped, nil
+
+}
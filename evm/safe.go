@@ -0,0 +1,199 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// SAFE_ABI are the parts of the Safe contract used by the relayer, txHash of the events is indexed since Safe 1.4
+const SAFE_ABI = `[{"inputs":[],"name":"nonce","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"owner","type":"address"}],"name":"isOwner","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"bytes32","name":"txHash","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"payment","type":"uint256"}],"name":"ExecutionSuccess","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"bytes32","name":"txHash","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"payment","type":"uint256"}],"name":"ExecutionFailure","type":"event"}]`
+
+// MULTISEND_ABI is the batching contract of Safe, called with delegatecall
+const MULTISEND_ABI = `[{"inputs":[{"internalType":"bytes","name":"transactions","type":"bytes"}],"name":"multiSend","outputs":[],"stateMutability":"payable","type":"function"}]`
+
+// SAFE_MULTISEND_CALL_ONLY is the canonical MultiSendCallOnly 1.3.0 deployment
+const SAFE_MULTISEND_CALL_ONLY = "0x40A2aCCbd92BCA938b02010E17A5b8929b49130D"
+
+const SAFE_OPERATION_CALL = 0
+const SAFE_OPERATION_DELEGATECALL = 1
+
+// SafeTx is a Safe transaction without refunds, executed once enough owners signed it
+type SafeTx struct {
+	Safe      common.Address
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+	Operation uint8
+	Nonce     uint64
+}
+
+// SafeExecution is an ExecutionSuccess or ExecutionFailure event of a Safe
+type SafeExecution struct {
+	SafeTxHash  common.Hash
+	TxHash      string
+	BlockNumber uint64
+	Success     bool
+}
+
+// TypedData returns the EIP-712 message signed by the owners, the domain of Safe 1.3 and later
+func (tx *SafeTx) TypedData(chainID int) apitypes.TypedData {
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"SafeTx": {
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "data", Type: "bytes"},
+				{Name: "operation", Type: "uint8"},
+				{Name: "safeTxGas", Type: "uint256"},
+				{Name: "baseGas", Type: "uint256"},
+				{Name: "gasPrice", Type: "uint256"},
+				{Name: "gasToken", Type: "address"},
+				{Name: "refundReceiver", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "SafeTx",
+		Domain: apitypes.TypedDataDomain{
+			ChainId:           math.NewHexOrDecimal256(int64(chainID)),
+			VerifyingContract: tx.Safe.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"to":             tx.To.Hex(),
+			"value":          tx.Value.String(),
+			"data":           hexutil.Encode(tx.Data),
+			"operation":      fmt.Sprint(tx.Operation),
+			"safeTxGas":      "0",
+			"baseGas":        "0",
+			"gasPrice":       "0",
+			"gasToken":       ETH_ZERO_ADDRESS,
+			"refundReceiver": ETH_ZERO_ADDRESS,
+			"nonce":          fmt.Sprint(tx.Nonce),
+		},
+	}
+
+}
+
+// EncodeMultiSend packs the calls into a multiSend call, every call is executed with CALL
+func EncodeMultiSend(calls []ethereum.CallMsg) ([]byte, error) {
+
+	var packed bytes.Buffer
+	for _, call := range calls {
+		value := call.Value
+		if value == nil {
+			value = big.NewInt(0)
+		}
+		packed.WriteByte(SAFE_OPERATION_CALL)
+		packed.Write(call.To.Bytes())
+		packed.Write(common.BigToHash(value).Bytes())
+		packed.Write(common.BigToHash(big.NewInt(int64(len(call.Data)))).Bytes())
+		packed.Write(call.Data)
+	}
+
+	multiSendABI, err := abi.JSON(strings.NewReader(MULTISEND_ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	data, err := multiSendABI.Pack("multiSend", packed.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack multiSend call: %w", err)
+	}
+
+	return data, nil
+
+}
+
+// GetSafeNonce returns the nonce of the next Safe tx at the block, the latest block if nil
+func (e *EVMClient) GetSafeNonce(safe common.Address, blockNumber *big.Int) (uint64, error) {
+
+	safeABI, err := abi.JSON(strings.NewReader(SAFE_ABI))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	var result []interface{}
+	contract := bind.NewBoundContract(safe, safeABI, e.Client, nil, nil)
+	if err := contract.Call(&bind.CallOpts{Context: context.Background(), BlockNumber: blockNumber}, &result, "nonce"); err != nil {
+		return 0, fmt.Errorf("failed to get nonce of Safe %s: %w", safe.Hex(), err)
+	}
+
+	return (*abi.ConvertType(result[0], new(*big.Int)).(**big.Int)).Uint64(), nil
+}
+
+// IsSafeOwner returns true if the account is an owner of the Safe
+func (e *EVMClient) IsSafeOwner(safe common.Address, account common.Address) (bool, error) {
+
+	safeABI, err := abi.JSON(strings.NewReader(SAFE_ABI))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	var result []interface{}
+	contract := bind.NewBoundContract(safe, safeABI, e.Client, nil, nil)
+	if err := contract.Call(&bind.CallOpts{Context: context.Background()}, &result, "isOwner", account); err != nil {
+		return false, fmt.Errorf("failed to get owners of Safe %s: %w", safe.Hex(), err)
+	}
+
+	return *abi.ConvertType(result[0], new(bool)).(*bool), nil
+}
+
+// GetSafeExecutions retrieves executed Safe txs from start to end blocks
+func (e *EVMClient) GetSafeExecutions(safe common.Address, start uint64, end uint64) ([]*SafeExecution, error) {
+
+	safeABI, err := abi.JSON(strings.NewReader(SAFE_ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	success := safeABI.Events["ExecutionSuccess"].ID
+	failure := safeABI.Events["ExecutionFailure"].ID
+
+	logs, err := e.Client.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+		Addresses: []common.Address{safe},
+		Topics:    [][]common.Hash{{success, failure}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var executions []*SafeExecution
+	for _, vLog := range logs {
+
+		var safeTxHash common.Hash
+		switch {
+		case len(vLog.Topics) > 1:
+			safeTxHash = vLog.Topics[1]
+		case len(vLog.Data) >= common.HashLength:
+			safeTxHash = common.BytesToHash(vLog.Data[:common.HashLength])
+		default:
+			return nil, fmt.Errorf("malformed Safe execution event in tx %s", vLog.TxHash.Hex())
+		}
+
+		executions = append(executions, &SafeExecution{
+			SafeTxHash:  safeTxHash,
+			TxHash:      vLog.TxHash.Hex(),
+			BlockNumber: vLog.BlockNumber,
+			Success:     vLog.Topics[0] == success,
+		})
+	}
+
+	return executions, nil
+}
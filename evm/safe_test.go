@@ -0,0 +1,148 @@
+package evm
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// type hashes of Safe.sol 1.3 and later
+const safeDomainSeparatorTypehash = "0x47e79534a245952e8b16893a336b85a3d9ea9fa8c573f3d803afb92a79469218"
+const safeTxTypehash = "0xbb8310d486368db6bd6f849402fdd73ad53d316b5a4b2644ad6efe0f941286d8"
+
+func TestSafeTxHash(t *testing.T) {
+
+	// the type strings hash to the constants of the contract
+	if got := hexutil.Encode(crypto.Keccak256([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))); got != safeDomainSeparatorTypehash {
+		t.Fatalf("DOMAIN_SEPARATOR_TYPEHASH = %s, want %s", got, safeDomainSeparatorTypehash)
+	}
+	if got := hexutil.Encode(crypto.Keccak256([]byte("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"))); got != safeTxTypehash {
+		t.Fatalf("SAFE_TX_TYPEHASH = %s, want %s", got, safeTxTypehash)
+	}
+
+	safe := common.HexToAddress("0x5afe5afE5afE5afE5afE5aFe5aFe5Afe5Afe5AfE")
+	minter := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	// getTransactionHash of the contract, encoded by hand
+	word := func(v uint64) []byte { return common.BigToHash(new(big.Int).SetUint64(v)).Bytes() }
+	address := func(a common.Address) []byte { return common.LeftPadBytes(a.Bytes(), 32) }
+	safeTxHash := func(chainID uint64, tx *SafeTx) []byte {
+		domainSeparator := crypto.Keccak256(hexutil.MustDecode(safeDomainSeparatorTypehash), word(chainID), address(safe))
+		structHash := crypto.Keccak256(
+			hexutil.MustDecode(safeTxTypehash),
+			address(tx.To),
+			common.BigToHash(tx.Value).Bytes(),
+			crypto.Keccak256(tx.Data),
+			word(uint64(tx.Operation)),
+			word(0),
+			word(0),
+			word(0),
+			address(common.Address{}),
+			address(common.Address{}),
+			word(tx.Nonce),
+		)
+		return crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+	}
+
+	tests := []struct {
+		name    string
+		chainID uint64
+		tx      *SafeTx
+	}{
+		{
+			name:    "single mint",
+			chainID: 619001,
+			tx:      &SafeTx{Safe: safe, To: minter, Value: big.NewInt(0), Data: hexutil.MustDecode("0x40c10f19"), Operation: SAFE_OPERATION_CALL, Nonce: 0},
+		},
+		{
+			name:    "multiSend batch",
+			chainID: 1,
+			tx:      &SafeTx{Safe: safe, To: common.HexToAddress(SAFE_MULTISEND_CALL_ONLY), Value: big.NewInt(0), Data: hexutil.MustDecode("0x8d80ff0a"), Operation: SAFE_OPERATION_DELEGATECALL, Nonce: 42},
+		},
+		{
+			name:    "empty data",
+			chainID: 619001,
+			tx:      &SafeTx{Safe: safe, To: minter, Value: big.NewInt(1), Data: nil, Operation: SAFE_OPERATION_CALL, Nonce: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, _, err := apitypes.TypedDataAndHash(tt.tx.TypedData(int(tt.chainID)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := safeTxHash(tt.chainID, tt.tx); !bytes.Equal(hash, want) {
+				t.Errorf("safeTxHash = %x, want %x", hash, want)
+			}
+		})
+	}
+
+}
+
+func TestEncodeMultiSend(t *testing.T) {
+
+	first := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	second := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	tests := []struct {
+		name  string
+		calls []ethereum.CallMsg
+		want  string // the packed transactions: operation, to, value, data length, data
+	}{
+		{
+			name:  "one call",
+			calls: []ethereum.CallMsg{{To: &first, Data: hexutil.MustDecode("0xaabbccdd")}},
+			want: "00" + "1111111111111111111111111111111111111111" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000004" +
+				"aabbccdd",
+		},
+		{
+			name: "two calls with value",
+			calls: []ethereum.CallMsg{
+				{To: &first, Data: hexutil.MustDecode("0x01")},
+				{To: &second, Value: big.NewInt(255), Data: hexutil.MustDecode("0x0203")},
+			},
+			want: "00" + "1111111111111111111111111111111111111111" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"01" +
+				"00" + "2222222222222222222222222222222222222222" +
+				"00000000000000000000000000000000000000000000000000000000000000ff" +
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"0203",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			data, err := EncodeMultiSend(tt.calls)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			packed := hexutil.MustDecode("0x" + tt.want)
+			padded := common.RightPadBytes(packed, (len(packed)+31)/32*32)
+
+			// multiSend(bytes): selector, offset, length, padded bytes
+			want := "0x8d80ff0a" +
+				"0000000000000000000000000000000000000000000000000000000000000020" +
+				strings.TrimPrefix(hexutil.Encode(common.BigToHash(big.NewInt(int64(len(packed)))).Bytes()), "0x") +
+				strings.TrimPrefix(hexutil.Encode(padded), "0x")
+			if got := hexutil.Encode(data); got != want {
+				t.Errorf("multiSend call = %s, want %s", got, want)
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,82 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer signs txs of a single account
+type Signer interface {
+	// Address returns the account of the signer
+	Address() common.Address
+	// SignTx returns tx signed for chainID
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignTypedData returns the EIP-712 signature of data, r || s || v with v of 27 or 28
+	SignTypedData(data apitypes.TypedData) ([]byte, error)
+}
+
+// KeySigner signs with a private key held in memory
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner creates a signer of the private key
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *KeySigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	return sig, nil
+
+}
+
+// RecoverTypedData returns the account that signed the EIP-712 hash, v may be 0, 1, 27 or 28
+func RecoverTypedData(hash []byte, sig []byte) (common.Address, error) {
+
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d", len(sig))
+	}
+
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return crypto.PubkeyToAddress(*pub), nil
+
+}
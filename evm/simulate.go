@@ -0,0 +1,141 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ERC20_ERRORS_ABI lists custom errors of the OpenZeppelin token and access control contracts, the errors of a minter without minterABI
+const ERC20_ERRORS_ABI = `[
+	{"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"OwnableUnauthorizedAccount","type":"error"},
+	{"inputs":[{"internalType":"address","name":"account","type":"address"},{"internalType":"bytes32","name":"neededRole","type":"bytes32"}],"name":"AccessControlUnauthorizedAccount","type":"error"},
+	{"inputs":[{"internalType":"address","name":"receiver","type":"address"}],"name":"ERC20InvalidReceiver","type":"error"},
+	{"inputs":[{"internalType":"uint256","name":"increasedSupply","type":"uint256"},{"internalType":"uint256","name":"cap","type":"uint256"}],"name":"ERC20ExceededCap","type":"error"},
+	{"inputs":[],"name":"EnforcedPause","type":"error"}
+]`
+
+// permanentErrors are custom errors that revert a call the same way however long it waits, other reverts may pass later
+var permanentErrors = map[string]bool{
+	"ERC20InvalidReceiver": true,
+}
+
+// contractErrors are the custom errors of called contracts by chain and address, reverts of other contracts are reported undecoded
+var contractErrors = make(map[contractKey]map[[4]byte]abi.Error)
+var contractErrorsMu sync.RWMutex
+
+type contractKey struct {
+	chainID int
+	address common.Address
+}
+
+// RegisterErrors adds the custom errors of the JSON ABI to the contract, reverts of calls to it are decoded with them
+func RegisterErrors(chainID int, address common.Address, abiJSON string) error {
+
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	contractErrorsMu.Lock()
+	defer contractErrorsMu.Unlock()
+
+	key := contractKey{chainID, address}
+	if contractErrors[key] == nil {
+		contractErrors[key] = make(map[[4]byte]abi.Error)
+	}
+	for _, customErr := range parsed.Errors {
+		contractErrors[key][[4]byte(customErr.ID[:4])] = customErr
+	}
+
+	return nil
+
+}
+
+// RevertError is returned when the simulated call reverts
+type RevertError struct {
+	Reason    string
+	Permanent bool // decoded as an error retrying can not fix
+}
+
+func (e *RevertError) Error() string {
+	return "execution reverted: " + e.Reason
+}
+
+// EstimateTx simulates msg with eth_call and eth_estimateGas and returns the gas limit with the safety multiplier applied.
+// Calls that would revert return *RevertError with the decoded reason.
+func (e *EVMClient) EstimateTx(msg ethereum.CallMsg) (uint64, error) {
+
+	msg.Gas = 0
+
+	if _, err := e.Client.CallContract(context.Background(), msg, nil); err != nil {
+		return 0, e.decodeRevert(err, msg.To)
+	}
+
+	gas, err := e.Client.EstimateGas(context.Background(), msg)
+	if err != nil {
+		return 0, e.decodeRevert(err, msg.To)
+	}
+
+	gas = uint64(float64(gas) * e.GasMultiplier)
+	if gas > e.GasLimit {
+		return 0, fmt.Errorf("estimated gas %d exceeds gasLimit %d", gas, e.GasLimit)
+	}
+
+	return gas, nil
+
+}
+
+// decodeRevert turns a reverted call error into *RevertError, decoding Error(string), Panic(uint256) and custom errors registered for the called contract
+func (e *EVMClient) decodeRevert(err error, to *common.Address) error {
+
+	if !isExecutionReverted(err) {
+		return err
+	}
+
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return &RevertError{Reason: err.Error()}
+	}
+
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return &RevertError{Reason: err.Error()}
+	}
+
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil || len(data) < 4 {
+		return &RevertError{Reason: err.Error()}
+	}
+
+	if reason, unpackErr := abi.UnpackRevert(data); unpackErr == nil {
+		return &RevertError{Reason: reason}
+	}
+
+	var customErr abi.Error
+	found := false
+	if to != nil {
+		contractErrorsMu.RLock()
+		customErr, found = contractErrors[contractKey{e.ChainID, *to}][[4]byte(data[:4])]
+		contractErrorsMu.RUnlock()
+	}
+	if !found {
+		return &RevertError{Reason: "unknown error " + hexData}
+	}
+
+	args, unpackErr := customErr.Unpack(data)
+	if unpackErr != nil {
+		return &RevertError{Reason: customErr.Sig, Permanent: permanentErrors[customErr.Name]}
+	}
+
+	return &RevertError{Reason: fmt.Sprintf("%s%v", customErr.Name, args), Permanent: permanentErrors[customErr.Name]}
+
+}
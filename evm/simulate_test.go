@@ -0,0 +1,65 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// revertData is the error of a reverted eth_call with its revert data
+type revertData string
+
+func (e revertData) Error() string          { return "execution reverted" }
+func (e revertData) ErrorData() interface{} { return string(e) }
+
+func TestDecodeRevert(t *testing.T) {
+
+	minter := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	const minterABI = `[{"inputs":[{"internalType":"uint256","name":"limit","type":"uint256"}],"name":"MintLimitExceeded","type":"error"},` +
+		`{"inputs":[{"internalType":"address","name":"receiver","type":"address"}],"name":"ERC20InvalidReceiver","type":"error"}]`
+	if err := RegisterErrors(1, minter, minterABI); err != nil {
+		t.Fatal(err)
+	}
+
+	selector := func(sig string) string { return hexutil.Encode(crypto.Keccak256([]byte(sig))[:4]) }
+	limitExceeded := selector("MintLimitExceeded(uint256)") + "00000000000000000000000000000000000000000000000000000000000000ff"
+	invalidReceiver := selector("ERC20InvalidReceiver(address)") + "0000000000000000000000000000000000000000000000000000000000000000"
+	errorString := selector("Error(string)") +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000"
+
+	tests := []struct {
+		name      string
+		chainID   int
+		to        *common.Address
+		data      string
+		want      string
+		permanent bool
+	}{
+		{name: "custom error of the called contract", chainID: 1, to: &minter, data: limitExceeded, want: "execution reverted: MintLimitExceeded[255]"},
+		{name: "custom error of another contract", chainID: 1, to: &other, data: limitExceeded, want: "execution reverted: unknown error " + limitExceeded},
+		{name: "same address on another chain", chainID: 2, to: &minter, data: limitExceeded, want: "execution reverted: unknown error " + limitExceeded},
+		{name: "no called contract", chainID: 1, data: limitExceeded, want: "execution reverted: unknown error " + limitExceeded},
+		{name: "Error(string)", chainID: 1, to: &other, data: errorString, want: "execution reverted: nope"},
+		{name: "permanent custom error", chainID: 1, to: &minter, data: invalidReceiver, want: "execution reverted: ERC20InvalidReceiver[0x0000000000000000000000000000000000000000]", permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EVMClient{ChainID: tt.chainID}
+			err := e.decodeRevert(revertData(tt.data), tt.to)
+			if got := err.Error(); got != tt.want {
+				t.Errorf("decodeRevert = %q, want %q", got, tt.want)
+			}
+			if permanent := err.(*RevertError).Permanent; permanent != tt.permanent {
+				t.Errorf("permanent = %v, want %v", permanent, tt.permanent)
+			}
+		})
+	}
+
+}
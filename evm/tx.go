@@ -2,103 +2,272 @@ package evm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// PrepareTx fills gas params for legacyTx depending on the txType of the client
-func (e *EVMClient) PrepareTx(legacyTx *types.LegacyTx) *types.Transaction {
+const ERC20_MINT_ABI = `[{"inputs":[{"internalType":"address","name":"account","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"mint","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
 
-	tx := &types.Transaction{}
+// Txs go through four stages, so the signature always covers the exact envelope that is broadcast:
+// build (call without gas and nonce) -> fill gas (envelope of the client txType) -> sign -> send
+// Callers simulate the built call with EstimateTx before filling gas.
 
-	if e.TxType == 0 {
-		legacyTx.GasPrice = e.GasFeeCap
-		legacyTx.Gas = e.GasLimit
-		tx = types.NewTx(legacyTx)
+// BuildERC20Mint builds the mint(address,uint256) call of the token
+func (e *EVMClient) BuildERC20Mint(contractAddr, recipient common.Address, amount *big.Int) (ethereum.CallMsg, error) {
+
+	erc20ABI, err := abi.JSON(strings.NewReader(ERC20_MINT_ABI))
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	data, err := erc20ABI.Pack("mint", recipient, amount)
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("failed to pack mint call: %w", err)
+	}
+
+	return ethereum.CallMsg{
+		From:  e.PublicKey,
+		To:    &contractAddr,
+		Value: big.NewInt(0),
+		Data:  data,
+	}, nil
+
+}
+
+// FillTx wraps msg into the envelope of the client txType with nonce and gas params, gasLimit is used if msg has no gas set
+func (e *EVMClient) FillTx(msg ethereum.CallMsg, nonce uint64, fees *Fees) (*types.Transaction, error) {
+
+	gas := msg.Gas
+	if gas == 0 {
+		gas = e.GasLimit
 	}
 
-	if e.TxType == 1 {
-		tx = types.NewTx(&types.DynamicFeeTx{
+	switch e.TxType {
+
+	case TX_TYPE_LEGACY:
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: fees.GasPrice,
+			Gas:      gas,
+			To:       msg.To,
+			Value:    msg.Value,
+			Data:     msg.Data,
+		}), nil
+
+	case TX_TYPE_ACCESS_LIST:
+		accessList, err := e.CreateAccessList(msg)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    big.NewInt(int64(e.ChainID)),
+			Nonce:      nonce,
+			GasPrice:   fees.GasPrice,
+			Gas:        gas,
+			To:         msg.To,
+			Value:      msg.Value,
+			Data:       msg.Data,
+			AccessList: accessList,
+		}), nil
+
+	case TX_TYPE_DYNAMIC_FEE:
+		return types.NewTx(&types.DynamicFeeTx{
 			ChainID:   big.NewInt(int64(e.ChainID)),
-			GasFeeCap: e.GasFeeCap,
-			GasTipCap: e.GasTipCap,
-			Gas:       e.GasLimit,
-			To:        legacyTx.To,
-			Value:     legacyTx.Value,
-			Data:      legacyTx.Data,
-			Nonce:     legacyTx.Nonce,
-		})
+			Nonce:     nonce,
+			GasTipCap: fees.GasTipCap,
+			GasFeeCap: fees.GasFeeCap,
+			Gas:       gas,
+			To:        msg.To,
+			Value:     msg.Value,
+			Data:      msg.Data,
+		}), nil
+
 	}
 
-	return tx
+	return nil, fmt.Errorf("unsupported txType %d", e.TxType)
 
 }
 
-// SubmitTx adds signature and submits tx
-func (e *EVMClient) SubmitTx(tx *types.Transaction, signature []byte) (common.Hash, error) {
+// CreateAccessList asks the node for the storage slots the call touches, so they can be prepaid in an EIP-2930 tx
+func (e *EVMClient) CreateAccessList(msg ethereum.CallMsg) (types.AccessList, error) {
 
-	signedTx, err := tx.WithSignature(types.LatestSignerForChainID(big.NewInt(int64(e.ChainID))), signature)
-	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to apply signature: %w", err)
+	args := map[string]interface{}{
+		"from":  msg.From,
+		"to":    msg.To,
+		"value": (*hexutil.Big)(msg.Value),
+		"input": hexutil.Bytes(msg.Data),
+	}
+
+	var result struct {
+		AccessList types.AccessList `json:"accessList"`
+		Error      string           `json:"error,omitempty"`
 	}
 
-	if err = e.Client.SendTransaction(context.Background(), signedTx); err != nil {
-		return common.Hash{}, fmt.Errorf("failed to send transaction: %w", err)
+	if err := e.Client.Client().CallContext(context.Background(), &result, "eth_createAccessList", args, "pending"); err != nil {
+		return nil, fmt.Errorf("failed to create access list: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("failed to create access list: %s", result.Error)
 	}
 
-	return signedTx.Hash(), nil
+	return result.AccessList, nil
 
 }
 
-func (e *EVMClient) GenerateAndSignERC20Mint(contractAddr, recipient common.Address, amount *big.Int) (*types.LegacyTx, []byte, error) {
-	// Parse ERC20 ABI with mint(address,uint256)
-	erc20ABI, err := abi.JSON(strings.NewReader(`[{"inputs":[{"internalType":"address","name":"account","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"mint","outputs":[],"stateMutability":"nonpayable","type":"function"}]`))
+// SignTx signs the final envelope, the hash of the result can be recorded before sending
+func (e *EVMClient) SignTx(tx *types.Transaction) (*types.Transaction, error) {
+
+	signedTx, err := e.Signer.SignTx(tx, big.NewInt(int64(e.ChainID)))
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse ABI: %w", err)
+		return nil, fmt.Errorf("failed to sign tx: %w", err)
 	}
 
-	// Encode call data
-	data, err := erc20ABI.Pack("mint", recipient, amount)
+	return signedTx, nil
+
+}
+
+// NewSignedTx runs the fill gas and sign stages for msg with fresh fees and a newly allocated nonce.
+// The caller must release the nonce if the tx is never broadcast.
+func (e *EVMClient) NewSignedTx(msg ethereum.CallMsg) (*types.Transaction, error) {
+
+	// fees are refreshed for every tx
+	fees, err := e.SuggestFees()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := e.Nonces().Acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := e.FillTx(msg, nonce, fees)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to pack mint call: %w", err)
+		e.Nonces().Release(nonce)
+		return nil, err
+	}
+
+	signedTx, err := e.SignTx(tx)
+	if err != nil {
+		e.Nonces().Release(nonce)
+		return nil, err
+	}
+
+	return signedTx, nil
+
+}
+
+// SendTx submits signed tx
+func (e *EVMClient) SendTx(signedTx *types.Transaction) error {
+
+	if err := e.Client.SendTransaction(context.Background(), signedTx); err != nil {
+		// the local nonce view is behind the node
+		if isNonceTooLow(err) {
+			e.Nonces().Invalidate()
+		}
+		return fmt.Errorf("failed to send transaction: %w", err)
 	}
 
-	// Get current nonce
-	nonce, err := e.Client.PendingNonceAt(context.Background(), e.PublicKey)
+	return nil
+
+}
+
+// GetReceipt returns the receipt of the tx, nil if it is not mined yet
+func (e *EVMClient) GetReceipt(txHash common.Hash) (*types.Receipt, error) {
+
+	receipt, err := e.Client.TransactionReceipt(context.Background(), txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get nonce: %w", err)
+		return nil, fmt.Errorf("failed to get receipt of %s: %w", txHash.Hex(), err)
+	}
+
+	return receipt, nil
+
+}
+
+// GetReceiptFromAny returns the receipt of the tx from the endpoint or any of the check endpoints, nil if none of them has it
+func (e *EVMClient) GetReceiptFromAny(txHash common.Hash) (*types.Receipt, error) {
+
+	receipt, err := e.GetReceipt(txHash)
+	if err != nil || receipt != nil {
+		return receipt, err
+	}
+
+	for i, client := range e.CheckClients {
+		receipt, err := client.TransactionReceipt(context.Background(), txHash)
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipt of %s from %s: %w", txHash.Hex(), e.CheckEndpoints[i], err)
+		}
+		return receipt, nil
 	}
 
-	// Create legacy tx
-	legacyTx := &types.LegacyTx{
-		Nonce:    nonce,
-		To:       &contractAddr,
-		Value:    big.NewInt(0),
-		Gas:      e.GasLimit,
-		GasPrice: e.GasFeeCap,
-		Data:     data,
+	return nil, nil
+
+}
+
+// GetNonce returns the nonce of the address in the latest block
+func (e *EVMClient) GetNonce(address common.Address) (uint64, error) {
+
+	nonce, err := e.Client.NonceAt(context.Background(), address, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get nonce: %w", err)
 	}
 
-	// Wrap it into a full tx so we can hash & sign
-	tx := types.NewTx(legacyTx)
+	return nonce, nil
 
-	// Get signer for the current chain ID
-	signer := types.LatestSignerForChainID(big.NewInt(int64(e.ChainID)))
+}
 
-	// Hash the tx
-	txHash := signer.Hash(tx).Bytes()
+// GetRevertReason replays the reverted tx in the block it was mined in and returns the revert reason
+func (e *EVMClient) GetRevertReason(tx *types.Transaction, blockNumber *big.Int) (string, error) {
 
-	// Sign it
-	signature, err := crypto.Sign(txHash, e.PrivateKey)
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to sign tx: %w", err)
+		return "", fmt.Errorf("failed to recover sender: %w", err)
+	}
+
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+
+	_, err = e.Client.CallContract(context.Background(), msg, blockNumber)
+	if err == nil {
+		// the replay did not revert, the tx most likely ran out of gas
+		return "reverted without reason", nil
+	}
+	var revertErr *RevertError
+	if errors.As(e.decodeRevert(err, tx.To()), &revertErr) {
+		return revertErr.Reason, nil
 	}
 
-	return legacyTx, signature, nil
+	return "", err
+
+}
+
+// DecodeTx decodes a signed tx from its binary encoding
+func DecodeTx(raw []byte) (*types.Transaction, error) {
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode tx: %w", err)
+	}
+
+	return tx, nil
+
 }
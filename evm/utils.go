@@ -1,8 +1,43 @@
 package evm
 
-import "strings"
+import (
+	"math/big"
+	"strings"
+)
 
 // isExecutionReverted checks if the error is a contract revert
 func isExecutionReverted(err error) bool {
 	return strings.Contains(err.Error(), "execution reverted")
 }
+
+// isNonceTooLow checks if the error is a rejected nonce that was already used
+func isNonceTooLow(err error) bool {
+	return strings.Contains(err.Error(), "nonce too low")
+}
+
+// gweiToWei converts gwei from config to wei
+func gweiToWei(gwei float64) *big.Int {
+	wei := big.NewFloat(gwei)
+	wei.Mul(wei, big.NewFloat(GWEI))
+	result, _ := wei.Int(nil)
+	return result
+}
+
+// ScaleAmount converts amount between token decimals.
+// Scaling down returns the remainder that can not be represented with fewer decimals as dust, in source units.
+func ScaleAmount(amount *big.Int, from uint8, to uint8) (*big.Int, *big.Int) {
+
+	if from == to {
+		return new(big.Int).Set(amount), big.NewInt(0)
+	}
+
+	if to > from {
+		factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to-from)), nil)
+		return new(big.Int).Mul(amount, factor), big.NewInt(0)
+	}
+
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(from-to)), nil)
+	scaled, dust := new(big.Int).QuoRem(amount, factor, new(big.Int))
+
+	return scaled, dust
+}
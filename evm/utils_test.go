@@ -0,0 +1,39 @@
+package evm
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestScaleAmount(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		amount string
+		from   uint8
+		to     uint8
+		scaled string
+		dust   string
+	}{
+		{name: "same decimals", amount: "123456789", from: 18, to: 18, scaled: "123456789", dust: "0"},
+		{name: "up", amount: "1234567", from: 6, to: 18, scaled: "1234567000000000000", dust: "0"},
+		{name: "down exact", amount: "1234567000000000000", from: 18, to: 6, scaled: "1234567", dust: "0"},
+		{name: "down with dust", amount: "1234567890123456789", from: 18, to: 6, scaled: "1234567", dust: "890123456789"},
+		{name: "down below precision", amount: "999999999999", from: 18, to: 6, scaled: "0", dust: "999999999999"},
+		{name: "zero", amount: "0", from: 18, to: 6, scaled: "0", dust: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, _ := new(big.Int).SetString(tt.amount, 10)
+			scaled, dust := ScaleAmount(amount, tt.from, tt.to)
+			if scaled.String() != tt.scaled || dust.String() != tt.dust {
+				t.Errorf("ScaleAmount = %s, %s, want %s, %s", scaled, dust, tt.scaled, tt.dust)
+			}
+			if amount.String() != tt.amount {
+				t.Errorf("amount changed to %s", amount)
+			}
+		})
+	}
+
+}
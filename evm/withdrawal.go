@@ -0,0 +1,173 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// REBASE_TOKEN_REDEEM_ABI is the default burn event of the rebase token, chainId is the chain where the burned amount is released.
+// Neither the event nor release are part of the Bridge ABI in binding, redeemABI and releaseABI of the bridge config replace them
+const REBASE_TOKEN_REDEEM_ABI = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":true,"internalType":"address","name":"receiver","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"chainId","type":"uint256"}],"name":"Redeem","type":"event"}]`
+
+// BRIDGE_RELEASE_ABI is the default method of the source chain bridge that pays out redeemed amounts
+const BRIDGE_RELEASE_ABI = `[{"inputs":[{"internalType":"address","name":"receiver","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"release","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// WithdrawalABI is the Redeem event of the rebase token and the release method of the bridge.
+// The event must have an address receiver, uint256 amount and uint256 chainId, an address bridge if several bridges of a chain share the token;
+// the method must be release(address receiver, uint256 amount)
+type WithdrawalABI struct {
+	redeem   abi.Event
+	release  abi.Method
+	byBridge bool // Redeem names the bridge releasing the amount
+}
+
+// NewWithdrawalABI parses and checks the ABIs, empty ones are the defaults
+func NewWithdrawalABI(redeemJSON string, releaseJSON string) (*WithdrawalABI, error) {
+
+	if redeemJSON == "" {
+		redeemJSON = REBASE_TOKEN_REDEEM_ABI
+	}
+	if releaseJSON == "" {
+		releaseJSON = BRIDGE_RELEASE_ABI
+	}
+
+	redeemABI, err := abi.JSON(strings.NewReader(redeemJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redeemABI: %w", err)
+	}
+	releaseABI, err := abi.JSON(strings.NewReader(releaseJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse releaseABI: %w", err)
+	}
+
+	w := &WithdrawalABI{}
+
+	var ok bool
+	if w.redeem, ok = redeemABI.Events["Redeem"]; !ok {
+		return nil, errors.New("redeemABI has no Redeem event")
+	}
+	types := make(map[string]string)
+	for _, arg := range w.redeem.Inputs {
+		types[arg.Name] = arg.Type.String()
+	}
+	for name, typ := range map[string]string{"receiver": "address", "amount": "uint256", "chainId": "uint256"} {
+		if types[name] != typ {
+			return nil, fmt.Errorf("Redeem event of redeemABI needs %s %s", typ, name)
+		}
+	}
+	if typ, exists := types["bridge"]; exists {
+		if typ != "address" {
+			return nil, errors.New("bridge of the Redeem event of redeemABI must be an address")
+		}
+		w.byBridge = true
+	}
+
+	if w.release, ok = releaseABI.Methods["release"]; !ok {
+		return nil, errors.New("releaseABI has no release method")
+	}
+	if len(w.release.Inputs) != 2 || w.release.Inputs[0].Type.String() != "address" || w.release.Inputs[1].Type.String() != "uint256" {
+		return nil, errors.New("release of releaseABI must be release(address,uint256)")
+	}
+
+	return w, nil
+
+}
+
+// ByBridge returns true if Redeem events name the bridge, so bridges of one chain can share the rebase token
+func (w *WithdrawalABI) ByBridge() bool {
+	return w.byBridge
+}
+
+// GetRedeemEvents retrieves Redeem events of the rebase token released by the bridge on chainID from start to end blocks
+func (e *EVMClient) GetRedeemEvents(w *WithdrawalABI, address string, chainID int, bridge string, start uint64, end *uint64) ([]*BridgeEvent, error) {
+
+	var events []*BridgeEvent
+
+	var indexed abi.Arguments
+	for _, arg := range w.redeem.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		Addresses: []common.Address{common.HexToAddress(address)},
+		Topics:    [][]common.Hash{{w.redeem.ID}},
+	}
+	if end != nil {
+		query.ToBlock = new(big.Int).SetUint64(*end)
+	}
+
+	logs, err := e.Client.FilterLogs(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vLog := range logs {
+
+		if len(vLog.Topics) != len(indexed)+1 {
+			return nil, fmt.Errorf("malformed Redeem event in tx %s", vLog.TxHash.Hex())
+		}
+
+		values := make(map[string]interface{})
+		if err := w.redeem.Inputs.UnpackIntoMap(values, vLog.Data); err != nil {
+			return nil, err
+		}
+		if err := abi.ParseTopicsIntoMap(values, indexed, vLog.Topics[1:]); err != nil {
+			return nil, err
+		}
+
+		if values["chainId"].(*big.Int).Cmp(big.NewInt(int64(chainID))) != 0 {
+			continue
+		}
+		if w.byBridge && values["bridge"].(common.Address) != common.HexToAddress(bridge) {
+			continue
+		}
+
+		header, err := e.Client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(vLog.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		// the chain was reorganized while logs were fetched
+		if header.Hash() != vLog.BlockHash {
+			return nil, fmt.Errorf("block %d was reorganized during scan", vLog.BlockNumber)
+		}
+
+		events = append(events, &BridgeEvent{
+			Timestamp:   header.Time,
+			BlockNumber: vLog.BlockNumber,
+			BlockHash:   vLog.BlockHash.Hex(),
+			TxHash:      vLog.TxHash.Hex(),
+			LogIndex:    vLog.Index,
+			Receiver:    values["receiver"].(common.Address).Hex(),
+			Amount:      values["amount"].(*big.Int),
+		})
+	}
+
+	return events, nil
+}
+
+// BuildRelease builds the release call of the source chain bridge
+func (e *EVMClient) BuildRelease(w *WithdrawalABI, bridgeAddr, receiver common.Address, amount *big.Int) (ethereum.CallMsg, error) {
+
+	args, err := w.release.Inputs.Pack(receiver, amount)
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("failed to pack release call: %w", err)
+	}
+
+	return ethereum.CallMsg{
+		From:  e.PublicKey,
+		To:    &bridgeAddr,
+		Value: big.NewInt(0),
+		Data:  append(append([]byte(nil), w.release.ID...), args...),
+	}, nil
+
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const EXECUTOR_INTERVAL = 10 * time.Second
+
+// executeRoutes executes deposits recorded in the ledger by the bridge scanners
+func executeRoutes(routes []*route, die chan bool) {
+
+	for {
+
+		select {
+		default:
+
+			time.Sleep(EXECUTOR_INTERVAL)
+
+			for _, r := range routes {
+				executeDeposits(r)
+			}
+
+		case <-die:
+			return
+		}
+
+	}
+
+}
+
+// executeDeposits mints (or releases, for withdrawals) all pending deposits of the route recorded in the ledger
+func executeDeposits(r *route) {
+
+	deposits := store.Ledger.GetDeposits(r.chainID, r.address, store.DepositPending)
+
+	// mints of Safe bridges are proposed in batches after the checks
+	var proposals []*store.Deposit
+
+	for _, deposit := range deposits {
+
+		// the breaker may trip on any deposit of the route
+		if pause := r.paused(); pause != nil {
+			log.WithField("prefix", "executor").Debug("Skipping ", r.direction, "s of bridge ", r.bridge.Address, ", ", pause.Scope, " paused: ", pause.Reason)
+			return
+		}
+
+		// withdrawals of a rebase token shared by several bridges
+		if deposit.TargetChainID != 0 && deposit.TargetChainID != r.target.ChainID {
+			continue
+		}
+		if deposit.Bridge != "" && !strings.EqualFold(deposit.Bridge, r.bridge.Address) {
+			continue
+		}
+
+		// fee mints wait for the mint of their deposit, which may be held or rejected
+		if deposit.Kind == store.DepositKindFee {
+			parent, err := store.Ledger.GetDeposit(deposit.ChainID, deposit.Address, deposit.TxHash, deposit.LogIndex)
+			if err != nil || (parent.Status != store.DepositSubmitted && parent.Status != store.DepositMined && parent.Status != store.DepositAttested && parent.Status != store.DepositProposed) {
+				continue
+			}
+		}
+
+		// scale deposits recorded before amounts were converted
+		if deposit.MintAmount == nil {
+			deposit.MintAmount, deposit.Dust = r.convert(deposit.Amount)
+		}
+
+		// too small to bridge, or the whole amount after fees is dust
+		var skip string
+		if deposit.Kind == "" && r.fees.belowMinimum(deposit.Amount) {
+			skip = "amount below minimum deposit"
+		} else if deposit.MintAmount.Sign() == 0 {
+			skip = "amount after fees below destination token precision"
+		}
+		if skip != "" {
+			log.WithField("prefix", "executor").Warn(r.direction, " ", deposit.ID(), " of ", deposit.Amount, " skipped: ", skip)
+			if _, err := store.Ledger.UpdateDeposit(deposit, deposit.Status, func(d *store.Deposit) {
+				d.Status = store.DepositSkipped
+				d.MintAmount = deposit.MintAmount
+				d.Dust = deposit.Dust
+				d.Error = skip
+			}); err != nil {
+				log.WithField("prefix", "executor").Error(err)
+			}
+			continue
+		}
+
+		// large amounts wait in the approval queue
+		if r.needsApproval(deposit) {
+			log.WithField("prefix", "executor").Warn(r.direction, " ", deposit.ID(), " of ", deposit.Amount, " is above the approval threshold, awaiting approval")
+			if _, err := store.Ledger.HoldDeposit(deposit, "amount above approval threshold"); err != nil {
+				log.WithField("prefix", "executor").Error(err)
+			}
+			continue
+		}
+
+		// blocked receivers wait in the approval queue, an approval only overrides the reason it was given for, fee mints go to the treasury
+		if deposit.Kind == "" {
+			if reason := screenReceiver(deposit.Receiver); reason != "" && !(deposit.Approved && reason == deposit.ApprovedReason) {
+				log.WithField("prefix", "executor").Error("ALERT: ", r.direction, " ", deposit.ID(), " blocked, awaiting approval: ", reason)
+				if _, err := store.Ledger.HoldDeposit(deposit, reason); err != nil {
+					log.WithField("prefix", "executor").Error(err)
+				}
+				continue
+			}
+		}
+
+		log.Info("Executing ", r.direction, " of ", deposit.MintAmount, " to ", deposit.Receiver, " on chain ", r.target.ChainID)
+
+		if r.safe != nil {
+			proposals = append(proposals, deposit)
+			continue
+		}
+
+		// in attestation mode the call carries signatures of M relayers
+		var signatures [][]byte
+		if attestations != nil {
+			var err error
+			signatures, err = attestations.attest(r, deposit)
+			if capReached(r, deposit, err) {
+				continue
+			}
+			if err != nil {
+				log.WithField("prefix", "executor").Error("failed to attest ", r.direction, " ", deposit.ID(), ": ", err)
+				continue
+			}
+			if signatures == nil {
+				continue
+			}
+		}
+
+		msg, err := r.buildCall(deposit, signatures)
+		if err != nil {
+			log.WithField("prefix", "executor").Error(err)
+			continue
+		}
+
+		// refuse to broadcast txs that would revert
+		msg.Gas, err = r.target.EstimateTx(msg)
+		var revertErr *evm.RevertError
+		if errors.As(err, &revertErr) {
+			log.WithField("prefix", "executor").Error("ALERT: ", r.direction, " ", deposit.ID(), " would revert: ", revertErr.Reason)
+			recordRevert(bridgeScope(r.bridge.Address))
+			if err := settleReverted(deposit, revertErr); err != nil {
+				log.WithField("prefix", "executor").Error(err)
+			}
+			continue
+		}
+		if err != nil {
+			log.WithField("prefix", "executor").Error(err)
+			continue
+		}
+
+		submitted, signedTx, err := submitDeposit(r, deposit, msg)
+		if capReached(r, deposit, err) {
+			continue
+		}
+		// orphaned or updated by another goroutine since it was read, the next pass sees the current record
+		if errors.Is(err, store.ErrDepositChanged) {
+			log.WithField("prefix", "executor").Warn("Skipping ", r.direction, " ", deposit.ID(), ": ", err)
+			continue
+		}
+		if err != nil {
+			log.WithField("prefix", "executor").Error("failed to submit ", r.direction, " ", deposit.ID(), ": ", err)
+			continue
+		}
+
+		// the tracker resolves the outcome, rebroadcasting or re-queueing the tx if needed
+		if sendErr := r.target.SendTx(signedTx); sendErr != nil {
+			log.WithField("prefix", "executor").Error("failed to send tx of ", r.direction, " ", deposit.ID(), ": ", sendErr)
+			if _, err := store.Ledger.UpdateDeposit(submitted, store.DepositSubmitted, func(d *store.Deposit) {
+				d.Error = sendErr.Error()
+			}); err != nil {
+				log.WithField("prefix", "executor").Error(err)
+			}
+			continue
+		}
+
+		log.Info("Tx sent: ", signedTx.Hash().Hex())
+
+	}
+
+	if len(proposals) > 0 {
+		proposeDeposits(r, proposals)
+	}
+
+}
+
+// settleReverted fails a deposit whose simulation reverted with a permanent error, other reverts hold it for the operator,
+// a paused or capped token may accept the mint later
+func settleReverted(deposit *store.Deposit, revertErr *evm.RevertError) error {
+
+	if !revertErr.Permanent {
+		_, err := store.Ledger.HoldDeposit(deposit, revertErr.Error())
+		return err
+	}
+
+	_, err := store.Ledger.UpdateDeposit(deposit, deposit.Status, func(d *store.Deposit) {
+		d.Status = store.DepositFailed
+		d.Error = revertErr.Error()
+	})
+	return err
+
+}
+
+// submitDeposit checks the caps, signs the tx and records it in the ledger, leaving it ready to be sent
+func submitDeposit(r *route, deposit *store.Deposit, msg ethereum.CallMsg) (*store.Deposit, *types.Transaction, error) {
+
+	// the cap usage must include this tx before the next check, signing does not block other bridges
+	if err := r.reserveCaps(deposit); err != nil {
+		return nil, nil, err
+	}
+	defer releaseCaps(deposit)
+
+	signedTx, err := r.target.NewSignedTx(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawTx, err := signedTx.MarshalBinary()
+	if err != nil {
+		r.target.Nonces().Release(signedTx.Nonce())
+		return nil, nil, err
+	}
+
+	// record the tx, it is sent after
+	submitted, err := store.Ledger.UpdateDeposit(deposit, deposit.Status, func(d *store.Deposit) {
+		d.Status = store.DepositSubmitted
+		d.MintChainID = r.target.ChainID
+		d.MintFrom = r.target.PublicKey.Hex()
+		d.MintTxHash = signedTx.Hash().Hex()
+		d.MintNonce = signedTx.Nonce()
+		d.MintRawTx = rawTx
+		d.MintAmount = deposit.MintAmount
+		d.Dust = deposit.Dust
+		d.SubmittedAt = time.Now()
+		d.ReplacedAt = time.Time{}
+		d.Error = ""
+	})
+	if err != nil {
+		r.target.Nonces().Release(signedTx.Nonce())
+		return nil, nil, fmt.Errorf("failed to record tx: %w", err)
+	}
+
+	return submitted, signedTx, nil
+
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+)
+
+const MAX_FEE_BPS = 10000
+
+// feePolicy is the fee charged on deposits of a bridge and the minimum deposit, amounts are in source token units
+type feePolicy struct {
+	fixed      *big.Int
+	bps        uint64
+	minDeposit *big.Int
+	treasury   string // fees are withheld if empty
+}
+
+// newFeePolicy parses the fee config of the bridge
+func newFeePolicy(b config.Bridge) (*feePolicy, error) {
+
+	p := &feePolicy{
+		fixed:      big.NewInt(0),
+		bps:        b.FeeBps,
+		minDeposit: big.NewInt(0),
+		treasury:   b.Treasury,
+	}
+
+	var ok bool
+	if b.FeeFixed != "" {
+		if p.fixed, ok = new(big.Int).SetString(b.FeeFixed, 10); !ok || p.fixed.Sign() < 0 {
+			return nil, fmt.Errorf("invalid feeFixed %s", b.FeeFixed)
+		}
+	}
+	if b.MinDeposit != "" {
+		if p.minDeposit, ok = new(big.Int).SetString(b.MinDeposit, 10); !ok || p.minDeposit.Sign() < 0 {
+			return nil, fmt.Errorf("invalid minDeposit %s", b.MinDeposit)
+		}
+	}
+	if p.bps > MAX_FEE_BPS {
+		return nil, fmt.Errorf("feeBps %d exceeds %d", p.bps, MAX_FEE_BPS)
+	}
+	if p.treasury != "" && !common.IsHexAddress(p.treasury) {
+		return nil, fmt.Errorf("invalid treasury %s", p.treasury)
+	}
+
+	return p, nil
+
+}
+
+// belowMinimum returns true if amount is too small to be bridged
+func (p *feePolicy) belowMinimum(amount *big.Int) bool {
+	return amount.Cmp(p.minDeposit) < 0
+}
+
+// charge splits amount into the bridged amount and the fee, the fee never exceeds amount
+func (p *feePolicy) charge(amount *big.Int) (*big.Int, *big.Int) {
+
+	fee := new(big.Int).Mul(amount, new(big.Int).SetUint64(p.bps))
+	fee.Div(fee, big.NewInt(MAX_FEE_BPS))
+	fee.Add(fee, p.fixed)
+
+	if fee.Cmp(amount) > 0 {
+		fee.Set(amount)
+	}
+
+	return new(big.Int).Sub(amount, fee), fee
+
+}
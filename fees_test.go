@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+)
+
+func TestFeePolicy(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		bridge  config.Bridge
+		amount  int64
+		below   bool
+		net     int64
+		fee     int64
+		wantErr bool
+	}{
+		{name: "no fee", amount: 1000, net: 1000, fee: 0},
+		{name: "bps", bridge: config.Bridge{FeeBps: 30}, amount: 100000, net: 99700, fee: 300},
+		{name: "bps rounds down", bridge: config.Bridge{FeeBps: 30}, amount: 999, net: 997, fee: 2},
+		{name: "fixed and bps", bridge: config.Bridge{FeeBps: 100, FeeFixed: "50"}, amount: 10000, net: 9850, fee: 150},
+		{name: "fee capped at amount", bridge: config.Bridge{FeeFixed: "500"}, amount: 400, net: 0, fee: 400},
+		{name: "full bps", bridge: config.Bridge{FeeBps: MAX_FEE_BPS}, amount: 1234, net: 0, fee: 1234},
+		{name: "below minimum", bridge: config.Bridge{MinDeposit: "1000"}, amount: 999, below: true, net: 999, fee: 0},
+		{name: "at minimum", bridge: config.Bridge{MinDeposit: "1000"}, amount: 1000, net: 1000, fee: 0},
+		{name: "bps above 100%", bridge: config.Bridge{FeeBps: MAX_FEE_BPS + 1}, wantErr: true},
+		{name: "negative fixed", bridge: config.Bridge{FeeFixed: "-1"}, wantErr: true},
+		{name: "invalid minimum", bridge: config.Bridge{MinDeposit: "1e18"}, wantErr: true},
+		{name: "invalid treasury", bridge: config.Bridge{Treasury: "treasury"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p, err := newFeePolicy(tt.bridge)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			amount := big.NewInt(tt.amount)
+			if got := p.belowMinimum(amount); got != tt.below {
+				t.Errorf("belowMinimum = %v, want %v", got, tt.below)
+			}
+			if tt.below {
+				return
+			}
+
+			net, fee := p.charge(amount)
+			if net.Int64() != tt.net || fee.Int64() != tt.fee {
+				t.Errorf("charge = %s, %s, want %d, %d", net, fee, tt.net, tt.fee)
+			}
+			if new(big.Int).Add(net, fee).Cmp(amount) != 0 {
+				t.Errorf("net %s and fee %s do not add up to %s", net, fee, amount)
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+)
+
+// PASSPHRASE_ENV supplies the keystore passphrase if no passphrase file is configured
+const PASSPHRASE_ENV = "AEVM_KEYSTORE_PASSPHRASE"
+
+// signers caches loaded signers, networks and bridges sharing a signer config share the signer
+var signers = make(map[config.Signer]evm.Signer)
+
+// envPassphrase is read from PASSPHRASE_ENV once, the variable is unset afterwards
+var envPassphrase *string
+
+// loadSigner creates the signer from the remote signer or keystore config, a plaintext privateKey is accepted only if insecure is set
+func loadSigner(dir string, conf *config.Signer, insecure bool) (evm.Signer, error) {
+
+	if signer, exists := signers[*conf]; exists {
+		return signer, nil
+	}
+
+	signer, err := newSigner(dir, conf, insecure)
+	if err != nil {
+		return nil, err
+	}
+
+	signers[*conf] = signer
+	return signer, nil
+
+}
+
+// newSigner creates the signer of the config
+func newSigner(dir string, conf *config.Signer, insecure bool) (evm.Signer, error) {
+
+	configured := 0
+	for _, v := range []string{conf.PrivateKey, conf.Keystore, conf.RemoteSigner} {
+		if v != "" {
+			configured++
+		}
+	}
+	if configured > 1 {
+		return nil, errors.New("only one of privateKey, keystore and remoteSigner can be configured")
+	}
+
+	if err := checkPlaintextKey(conf, insecure); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case conf.PrivateKey != "":
+		log.WithField("prefix", "main").Warn("Using plaintext privateKey from config")
+		key, err := crypto.HexToECDSA(conf.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		return evm.NewKeySigner(key), nil
+	case conf.RemoteSigner != "":
+		return evm.NewRemoteSigner(conf.RemoteSigner, conf.RemoteSignerAddress)
+	case conf.Keystore != "":
+		passphrase, err := readPassphrase(dir, conf)
+		if err != nil {
+			return nil, err
+		}
+		return evm.NewKeystoreSigner(resolvePath(dir, conf.Keystore), passphrase)
+	}
+
+	return nil, errors.New("no signer configured, set keystore or remoteSigner")
+
+}
+
+// checkPlaintextKey refuses a plaintext privateKey unless insecure is set
+func checkPlaintextKey(conf *config.Signer, insecure bool) error {
+
+	if conf.PrivateKey != "" && !insecure {
+		return errors.New("plaintext privateKey in config, move it to a keystore or run with -insecure-plaintext-key")
+	}
+
+	return nil
+
+}
+
+// readPassphrase returns the keystore passphrase from the passphrase file, the environment or an interactive prompt
+func readPassphrase(dir string, conf *config.Signer) (string, error) {
+
+	if conf.PassphraseFile != "" {
+		data, err := os.ReadFile(resolvePath(dir, conf.PassphraseFile))
+		if err != nil {
+			return "", fmt.Errorf("can not read passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if envPassphrase == nil {
+		if passphrase, ok := os.LookupEnv(PASSPHRASE_ENV); ok {
+			// keep the passphrase out of child processes and crash dumps
+			os.Unsetenv(PASSPHRASE_ENV)
+			envPassphrase = &passphrase
+		}
+	}
+	if envPassphrase != nil {
+		return *envPassphrase, nil
+	}
+
+	passphrase, err := promptPassphrase("Passphrase of " + conf.Keystore + ": ")
+	if err != nil {
+		return "", fmt.Errorf("no keystore passphrase, set passphraseFile or %s: %w", PASSPHRASE_ENV, err)
+	}
+
+	return passphrase, nil
+
+}
+
+// promptPassphrase reads the passphrase from the terminal on stdin without echoing it
+func promptPassphrase(prompt string) (string, error) {
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("stdin is not a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("can not read passphrase: %w", err)
+	}
+
+	return string(passphrase), nil
+
+}
+
+// resolvePath resolves file paths from config against the config dir
+func resolvePath(dir string, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(dir, file)
+}
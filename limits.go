@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const CAP_HOURLY_WINDOW = 1 * time.Hour
+const CAP_DAILY_WINDOW = 24 * time.Hour
+
+// ErrCapReached of the hourly or daily cap of a bridge pauses its mints with the breaker until an operator resumes them
+var ErrCapReached = errors.New("mint cap reached")
+
+// ErrGlobalCapReached is ErrCapReached of the cap across all bridges, it pauses every bridge
+var ErrGlobalCapReached = fmt.Errorf("global %w", ErrCapReached)
+
+// ErrReceiverCapReached defers only the mints of one receiver until enough of the rolling window has passed, other receivers keep minting
+var ErrReceiverCapReached = errors.New("receiver mint cap reached")
+
+// globalDailyCap limits mints of each destination token across all bridges minting it, nil if not configured
+var globalDailyCap *big.Int
+
+// capTokens are the destination tokens of the routes by capBridge, amounts of different tokens are never summed
+var capTokens = make(map[string]string)
+
+// capsMu serializes cap checks with recording of the checked mints, so concurrent bridges can not overshoot the global cap
+var capsMu sync.Mutex
+
+// reservedCaps are mints checked against the caps but not recorded in the ledger yet, by deposit ID, guarded by capsMu
+var reservedCaps = make(map[string]*store.Deposit)
+
+// mintCaps are rolling limits of mints or of releases of a bridge in destination token units, nil caps are not enforced
+type mintCaps struct {
+	hourly        *big.Int
+	daily         *big.Int
+	receiverDaily *big.Int
+}
+
+// newMintCaps parses the caps of the bridge
+func newMintCaps(b config.Bridge) (*mintCaps, error) {
+
+	var err error
+	caps := &mintCaps{}
+
+	if caps.hourly, err = parseCap("hourlyCap", b.HourlyCap); err != nil {
+		return nil, err
+	}
+	if caps.daily, err = parseCap("dailyCap", b.DailyCap); err != nil {
+		return nil, err
+	}
+	if caps.receiverDaily, err = parseCap("receiverDailyCap", b.ReceiverDailyCap); err != nil {
+		return nil, err
+	}
+
+	return caps, nil
+
+}
+
+// parseCap parses a cap from config, empty value means no cap
+func parseCap(name string, value string) (*big.Int, error) {
+
+	if value == "" {
+		return nil, nil
+	}
+
+	limit, ok := new(big.Int).SetString(value, 10)
+	if !ok || limit.Sign() < 0 {
+		return nil, fmt.Errorf("invalid %s %s", name, value)
+	}
+
+	return limit, nil
+
+}
+
+// capBridge keys a route in capTokens by the chain and address its deposits are recorded under
+func capBridge(chainID int, address string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(address))
+}
+
+// capToken keys the destination token of the bridge, the global cap applies per token
+func capToken(b config.Bridge) string {
+	return fmt.Sprintf("%d:%s", b.DestinationChainID, strings.ToLower(b.RebaseToken))
+}
+
+// reserveCaps checks the caps and counts the deposit until releaseCaps, so the mint can be signed without holding capsMu
+func (r *route) reserveCaps(deposit *store.Deposit) error {
+
+	capsMu.Lock()
+	defer capsMu.Unlock()
+
+	if err := r.checkCaps(deposit); err != nil {
+		return err
+	}
+
+	reserved := *deposit
+	reserved.SubmittedAt = time.Now()
+	reservedCaps[deposit.ID()] = &reserved
+
+	return nil
+
+}
+
+// releaseCaps stops counting a reservation, once the mint is recorded in the ledger or was never signed
+func releaseCaps(deposit *store.Deposit) {
+
+	capsMu.Lock()
+	defer capsMu.Unlock()
+
+	delete(reservedCaps, deposit.ID())
+
+}
+
+// checkCaps returns an error if minting the deposit would exceed any cap, the caller must hold capsMu until the mint is recorded or reserved.
+// Usage is summed from mints recorded in the ledger, so it survives restarts, and from reservations.
+func (r *route) checkCaps(deposit *store.Deposit) error {
+
+	now := time.Now()
+	token := capToken(r.bridge)
+
+	global := big.NewInt(0)
+	hourly := big.NewInt(0)
+	daily := big.NewInt(0)
+	receiverDaily := big.NewInt(0)
+
+	// mints and releases are capped separately
+	executed := store.Ledger.GetExecutedSince(r.direction, now.Add(-CAP_DAILY_WINDOW))
+	recorded := make(map[string]bool)
+	for _, d := range executed {
+		recorded[d.ID()] = true
+	}
+	for id, d := range reservedCaps {
+		if !recorded[id] && d.Direction == r.direction {
+			executed = append(executed, d)
+		}
+	}
+
+	for _, d := range executed {
+		// reserved by its own attestation
+		if d.ID() == deposit.ID() {
+			continue
+		}
+		amount := capAmount(d)
+		if capTokens[capBridge(d.ChainID, d.Address)] == token {
+			global.Add(global, amount)
+		}
+		// bridges sharing the rebase token record their withdrawals under the same address
+		if d.ChainID != r.chainID || !strings.EqualFold(d.Address, r.address) || !strings.EqualFold(d.Bridge, r.bridge.Address) {
+			continue
+		}
+		daily.Add(daily, amount)
+		if d.SubmittedAt.After(now.Add(-CAP_HOURLY_WINDOW)) {
+			hourly.Add(hourly, amount)
+		}
+		if d.Kind == "" && strings.EqualFold(d.Receiver, deposit.Receiver) {
+			receiverDaily.Add(receiverDaily, amount)
+		}
+	}
+
+	requested := capAmount(deposit)
+	if err := checkCap(ErrGlobalCapReached, "daily "+r.bridge.RebaseToken, globalDailyCap, global, requested); err != nil {
+		return err
+	}
+	if err := checkCap(ErrCapReached, "hourly", r.caps.hourly, hourly, requested); err != nil {
+		return err
+	}
+	if err := checkCap(ErrCapReached, "daily", r.caps.daily, daily, requested); err != nil {
+		return err
+	}
+	// fee mints to the treasury are limited by the bridge caps only
+	if deposit.Kind == "" {
+		if err := checkCap(ErrReceiverCapReached, "receiver daily", r.caps.receiverDaily, receiverDaily, requested); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// capAmount is the amount of the deposit counted against the caps: the mint of a deposit, the redeemed rebase tokens of a withdrawal
+func capAmount(d *store.Deposit) *big.Int {
+
+	if d.Direction == DIRECTION_WITHDRAWAL || d.MintAmount == nil {
+		return d.Amount
+	}
+
+	return d.MintAmount
+
+}
+
+// checkCap returns reached if used plus amount is above limit
+func checkCap(reached error, name string, limit *big.Int, used *big.Int, amount *big.Int) error {
+
+	if limit == nil {
+		return nil
+	}
+
+	if new(big.Int).Add(used, amount).Cmp(limit) > 0 {
+		return fmt.Errorf("%w: %s cap %s, %s already used, %s requested", reached, name, limit, used, amount)
+	}
+
+	return nil
+
+}
+
+// capReached handles a cap error of the deposit, tripping the breaker of the bridge or global cap, and returns false if err is not one
+func capReached(r *route, deposit *store.Deposit, err error) bool {
+
+	switch {
+	case errors.Is(err, ErrReceiverCapReached):
+		log.WithField("prefix", "limits").Warn("Deferring ", r.direction, " ", deposit.ID(), " to ", deposit.Receiver, ": ", err)
+	case errors.Is(err, ErrGlobalCapReached):
+		tripBreaker(store.BREAKER_SCOPE_GLOBAL, err.Error())
+	case errors.Is(err, ErrCapReached):
+		tripBreaker(bridgeScope(r.bridge.Address), err.Error())
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+func TestCheckCaps(t *testing.T) {
+
+	const bridgeA = "0x000000000000000000000000000000000000000A"
+	const bridgeB = "0x000000000000000000000000000000000000000B" // mints the same token on another chain
+	const bridgeC = "0x000000000000000000000000000000000000000C" // mints another token
+	const token = "0x0000000000000000000000000000000000000aaa"
+	const receiver1 = "0x0000000000000000000000000000000000000001"
+	const receiver2 = "0x0000000000000000000000000000000000000002"
+	const receiver3 = "0x0000000000000000000000000000000000000003"
+
+	ledger, err := store.NewLedgerStore(filepath.Join(t.TempDir(), "ledger.gob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousLedger, previousCap := store.Ledger, globalDailyCap
+	store.Ledger, globalDailyCap = ledger, big.NewInt(2500)
+	capTokens[capBridge(1, bridgeA)] = "619001:" + token
+	capTokens[capBridge(2, bridgeB)] = "619001:" + token
+	capTokens[capBridge(3, bridgeC)] = "619001:0x0000000000000000000000000000000000000ccc"
+	t.Cleanup(func() {
+		store.Ledger, globalDailyCap = previousLedger, previousCap
+		delete(capTokens, capBridge(1, bridgeA))
+		delete(capTokens, capBridge(2, bridgeB))
+		delete(capTokens, capBridge(3, bridgeC))
+	})
+
+	deposit := func(chainID int, bridge string, logIndex uint, receiver string, amount int64) *store.Deposit {
+		return &store.Deposit{
+			ChainID:    chainID,
+			Address:    bridge,
+			Bridge:     bridge,
+			Direction:  DIRECTION_DEPOSIT,
+			TxHash:     "0x00000000000000000000000000000000000000000000000000000000000000aa",
+			LogIndex:   logIndex,
+			Receiver:   receiver,
+			Amount:     big.NewInt(amount),
+			MintAmount: big.NewInt(amount),
+		}
+	}
+
+	// mined within the windows: bridge A hourly 600, daily 1000, receiver1 600, the token 2000
+	mined := []struct {
+		d   *store.Deposit
+		ago time.Duration
+	}{
+		{deposit(1, bridgeA, 1, receiver1, 600), 30 * time.Minute},
+		{deposit(1, bridgeA, 2, receiver2, 400), 3 * time.Hour},
+		{deposit(1, bridgeA, 3, receiver2, 9000), 25 * time.Hour},
+		{deposit(2, bridgeB, 1, receiver1, 1000), 20 * time.Minute},
+		{deposit(3, bridgeC, 1, receiver1, 5000), 20 * time.Minute},
+	}
+	for _, m := range mined {
+		added, _, err := ledger.AddDeposit(m.d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ledger.UpdateDeposit(added, store.DepositPending, func(d *store.Deposit) {
+			d.Status = store.DepositMined
+			d.SubmittedAt = time.Now().Add(-m.ago)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &route{
+		bridge:    config.Bridge{Address: bridgeA, RebaseToken: token, DestinationChainID: 619001},
+		direction: DIRECTION_DEPOSIT,
+		chainID:   1,
+		address:   bridgeA,
+		caps:      &mintCaps{hourly: big.NewInt(1000), daily: big.NewInt(2000), receiverDaily: big.NewInt(800)},
+	}
+
+	// a mint of receiver1 being signed adds 100 to bridge A, receiver1 and the token
+	reserved := deposit(1, bridgeA, 4, receiver1, 100)
+	if err := r.reserveCaps(reserved); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { releaseCaps(reserved) })
+
+	fee := deposit(1, bridgeA, 5, receiver1, 101)
+	fee.Kind = store.DepositKindFee
+
+	tests := []struct {
+		name    string
+		deposit *store.Deposit
+		wantErr error
+	}{
+		{name: "within every cap", deposit: deposit(1, bridgeA, 10, receiver2, 300)},
+		{name: "hourly cap", deposit: deposit(1, bridgeA, 11, receiver3, 301), wantErr: ErrCapReached},
+		{name: "receiver cap", deposit: deposit(1, bridgeA, 12, receiver1, 101), wantErr: ErrReceiverCapReached},
+		{name: "fee mint ignores the receiver cap", deposit: fee},
+		{name: "global cap of the token", deposit: deposit(1, bridgeA, 13, receiver2, 401), wantErr: ErrGlobalCapReached},
+		{name: "own reservation not counted", deposit: reserved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.checkCaps(tt.deposit)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// the receiver cap defers one deposit, it must not trip the bridge breaker
+			if errors.Is(tt.wantErr, ErrReceiverCapReached) && errors.Is(err, ErrCapReached) {
+				t.Errorf("receiver cap error %v wraps ErrCapReached", err)
+			}
+		})
+	}
+
+	// releases of bridge A are capped like its mints, separately from them, bridge B shares the rebase token
+	capTokens[capBridge(619001, token)] = "619001:" + token
+	t.Cleanup(func() { delete(capTokens, capBridge(619001, token)) })
+	withdrawals := &route{
+		bridge:    r.bridge,
+		direction: DIRECTION_WITHDRAWAL,
+		chainID:   619001,
+		address:   token,
+		caps:      r.caps,
+	}
+	release := func(bridge string, logIndex uint, receiver string, amount int64) *store.Deposit {
+		d := deposit(619001, token, logIndex, receiver, amount)
+		d.Bridge = bridge
+		d.Direction = DIRECTION_WITHDRAWAL
+		d.MintAmount = big.NewInt(1) // in source token units, not counted
+		return d
+	}
+
+	// released within the windows: bridge A hourly 700, daily 900, receiver1 700, the token 1900
+	released := []struct {
+		d   *store.Deposit
+		ago time.Duration
+	}{
+		{release(bridgeA, 1, receiver1, 700), 10 * time.Minute},
+		{release(bridgeA, 2, receiver2, 200), 2 * time.Hour},
+		{release(bridgeB, 3, receiver1, 1000), 10 * time.Minute},
+	}
+	for _, m := range released {
+		added, _, err := ledger.AddDeposit(m.d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ledger.UpdateDeposit(added, store.DepositPending, func(d *store.Deposit) {
+			d.Status = store.DepositMined
+			d.SubmittedAt = time.Now().Add(-m.ago)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	releases := []struct {
+		name    string
+		deposit *store.Deposit
+		wantErr error
+	}{
+		{name: "release within every cap", deposit: release(bridgeA, 10, receiver3, 300)},
+		{name: "release hourly cap", deposit: release(bridgeA, 11, receiver3, 301), wantErr: ErrCapReached},
+		{name: "release receiver cap", deposit: release(bridgeA, 12, receiver1, 101), wantErr: ErrReceiverCapReached},
+		{name: "release global cap of the token", deposit: release(bridgeA, 13, receiver3, 601), wantErr: ErrGlobalCapReached},
+	}
+
+	for _, tt := range releases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := withdrawals.checkCaps(tt.deposit)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+}
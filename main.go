@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/AccumulatedFinance/aevm-bridge/config"
@@ -15,6 +17,8 @@ import (
 )
 
 const CACHE_FILE = "cache.gob"
+const LEDGER_FILE = "ledger.gob"
+const BREAKER_FILE = "breaker.gob"
 
 func main() {
 
@@ -26,13 +30,24 @@ func main() {
 	dir := usr.HomeDir + "/.aevm"
 	flag.StringVar(&dir, "dir", dir, "dir path")
 
+	insecure := false
+	flag.BoolVar(&insecure, "insecure-plaintext-key", insecure, "accept a plaintext privateKey in config")
+
 	flag.Parse()
 
-	start(dir)
+	// subcommands talk to the running relayer
+	if flag.NArg() > 0 {
+		if err := runCommand(dir, flag.Args()); err != nil {
+			log.WithField("prefix", "main").Fatal(err)
+		}
+		return
+	}
+
+	start(dir, insecure)
 
 }
 
-func start(dir string) {
+func start(dir string, insecure bool) {
 
 	var err error
 	var conf *config.Config
@@ -47,27 +62,188 @@ func start(dir string) {
 	store.Data = store.NewDataStore(filepath.Join(dir, CACHE_FILE), conf)
 	store.EVM = store.NewEVMStore()
 
-	// init evm network
+	log.WithField("prefix", "main").Debug("initializing deposit ledger")
+	if store.Ledger, err = store.NewLedgerStore(filepath.Join(dir, LEDGER_FILE)); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+
+	log.WithField("prefix", "main").Debug("initializing circuit breaker")
+	if store.Breaker, err = store.NewBreakerStore(filepath.Join(dir, BREAKER_FILE)); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+	for _, pause := range store.Breaker.GetPauses() {
+		log.WithField("prefix", "main").Warn("Minting on ", pause.Scope, " is paused since ", pause.PausedAt.Format(time.RFC3339), ": ", pause.Reason)
+	}
+	maxReverts = conf.MaxReverts
+
+	// chains where bridges sign with the network signer, mints on the destination chain and releases on the source chain
+	signing := make(map[int][]string)
+	for _, b := range conf.Bridges {
+		if b.Signer != nil {
+			continue
+		}
+		signing[b.DestinationChainID] = append(signing[b.DestinationChainID], b.Address)
+		if b.Withdrawals {
+			signing[b.ChainID] = append(signing[b.ChainID], b.Address)
+		}
+	}
+
+	// the top level key is refused even if every network has its own signer
+	if err := checkPlaintextKey(conf.GetSigner(), insecure); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+
+	// init evm network, each network signs with its own signer or the top level one, networks no bridge signs on may have none
+	// signer keys are never stored unencrypted on disk
 	for _, v := range conf.EVMNetworks {
+		signerConf := v.Signer
+		if signerConf == nil {
+			signerConf = conf.GetSigner()
+		}
+		if *signerConf == (config.Signer{}) {
+			if bridges := signing[v.ChainID]; len(bridges) > 0 {
+				log.WithField("prefix", "main").Fatal("network ", v.ChainID, ": no signer configured, bridges ", strings.Join(bridges, ", "), " sign there")
+			}
+			client, err := evm.NewEVMClient(v)
+			if err != nil {
+				log.WithField("prefix", "main").Fatal(err)
+			}
+			store.EVM.AddClient(client)
+			log.WithField("prefix", "main").Info("Not signing on chain ", v.ChainID)
+			continue
+		}
+		signer, err := loadSigner(dir, signerConf, insecure)
+		if err != nil {
+			log.WithField("prefix", "main").Fatal("network ", v.ChainID, ": ", err)
+		}
 		client, err := evm.NewEVMClient(v)
 		if err != nil {
 			log.WithField("prefix", "main").Fatal(err)
 		}
-		client, err = client.ImportPrivateKey(conf.PrivateKey)
+		store.EVM.AddClient(client.SetSigner(signer))
+		log.WithField("prefix", "main").Info("Signing on chain ", v.ChainID, " with ", signer.Address().Hex())
+	}
+
+	// bridges with their own signer isolate their minter key from the network signer
+	for _, b := range conf.Bridges {
+		if b.Signer == nil {
+			continue
+		}
+		signer, err := loadSigner(dir, b.Signer, insecure)
 		if err != nil {
-			log.Fatal(err)
+			log.WithField("prefix", "main").Fatal("bridge ", b.Address, ": ", err)
+		}
+		chains := []int{b.DestinationChainID}
+		if b.Withdrawals {
+			chains = append(chains, b.ChainID)
+		}
+		for _, chainID := range chains {
+			client, err := store.EVM.GetClientByChainId(chainID)
+			if err != nil {
+				log.WithField("prefix", "main").Fatal("bridge ", b.Address, ": ", err)
+			}
+			store.EVM.AddBridgeClient(b.Address, client.WithSigner(signer))
+			log.WithField("prefix", "main").Info("Signing for bridge ", b.Address, " on chain ", chainID, " with ", signer.Address().Hex())
 		}
-		store.EVM.AddClient(client)
 	}
 
+	// nonces of mints submitted before restart are still in-flight
+	restoreNonces()
+
+	if globalDailyCap, err = parseCap("dailyCap", conf.DailyCap); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+
+	// receiver screening
+	if denylist, err = newAddressList(dir, conf.DenylistFile, true); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+	if allowlist, err = newAddressList(dir, conf.AllowlistFile, false); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+
+	// every bridge needs clients on both ends
+	var routes []*route
+	for _, b := range conf.Bridges {
+		r, err := newDepositRoute(b)
+		if err != nil {
+			log.WithField("prefix", "main").Fatal("bridge ", b.Address, ": ", err)
+		}
+		routes = append(routes, r)
+		capTokens[capBridge(r.chainID, r.address)] = capToken(b)
+		if b.Withdrawals {
+			if r, err = newWithdrawalRoute(b); err != nil {
+				log.WithField("prefix", "main").Fatal("bridge ", b.Address, ": ", err)
+			}
+			routes = append(routes, r)
+			capTokens[capBridge(r.chainID, r.address)] = capToken(b)
+		}
+	}
+
+	// a Redeem event without the bridge can not tell bridges of one chain sharing the rebase token apart
+	released := make(map[string]string)
+	for _, r := range routes {
+		if r.direction != DIRECTION_WITHDRAWAL || r.withdrawals.ByBridge() {
+			continue
+		}
+		key := fmt.Sprintf("%d:%s:%d", r.chainID, strings.ToLower(r.address), r.bridge.ChainID)
+		if other, exists := released[key]; exists {
+			log.WithField("prefix", "main").Fatal("bridges ", other, " and ", r.bridge.Address, " release withdrawals of ", r.address, " on chain ", r.bridge.ChainID, ", redeemABI must name the bridge")
+		}
+		released[key] = r.bridge.Address
+	}
+
+	// high-value bridges propose mints to their Safe
+	for _, r := range routes {
+		if r.direction != DIRECTION_DEPOSIT || r.bridge.Safe == nil {
+			continue
+		}
+		if r.safe, err = newSafeProposer(dir, r); err != nil {
+			log.WithField("prefix", "main").Fatal("bridge ", r.bridge.Address, ": ", err)
+		}
+	}
+
+	// threshold mode, mints wait for attestations of other relayers
+	if attestations, err = newAttestor(dir, conf.Attestation); err != nil {
+		log.WithField("prefix", "main").Fatal(err)
+	}
+	if attestations != nil {
+		if err := attestations.check(routes); err != nil {
+			log.WithField("prefix", "main").Fatal(err)
+		}
+	}
+
+	// snapshot on-chain configuration of the bridges before anything is executed
+	watchers := newWatchers(routes)
+
 	die := make(chan bool)
 
 	// init bridges
-	bridges := &conf.Bridges
-	for _, b := range *bridges {
-		go getBridge(b, die)
+	for _, r := range routes {
+		go getBridge(r, die)
 	}
 
+	// alert on changes of the bridge contracts
+	go watchBridges(watchers, die)
+
+	// hot reload of screening lists
+	go reloadLists(die)
+
+	// reconcile deposits, mints and token supply
+	go auditBridges(newAuditor(routes), die)
+
+	// operator access to the approval queue
+	startAdmin(conf)
+
+	// execute recorded deposits
+	go executeRoutes(routes, die)
+
+	// track outcomes of submitted mints
+	go trackMints(die)
+
+	// track executions of mints proposed to Safes
+	go trackProposals(routes, die)
+
 	// persistent cache of the data store every minute
 	go func() {
 		for {
@@ -83,40 +259,28 @@ func start(dir string) {
 
 }
 
-// getBridge parses presale deposit events
-func getBridge(p config.Bridge, die chan bool) {
+// getBridge parses bridge events of the route and records them in the ledger for the executor
+func getBridge(r *route, die chan bool) {
 
-	log.Info("Parsing bridge on chain ", p.ChainID)
+	log.Info("Parsing ", r.direction, "s of bridge ", r.bridge.Address, " on chain ", r.chainID)
 
 	var firstBlock uint64
 	var lastBlock uint64
 
-	bridgeBlock, err := store.Data.GetBlock(p.ChainID, p.Address)
+	bridgeBlock, err := store.Data.GetBlock(r.chainID, r.cursor)
 	if err != nil {
-		firstBlock = p.BlockNumber
+		firstBlock = r.startBlock
 	} else {
 		firstBlock = bridgeBlock
 		// if received higher blocknumber from config, ignore cache
-		if p.BlockNumber > bridgeBlock {
-			firstBlock = p.BlockNumber
+		if r.startBlock > bridgeBlock {
+			firstBlock = r.startBlock
 		}
 	}
 
 	timeout := int64(5)
 	//	val := validation.GetInstance()
 
-	client, err := store.EVM.GetClientByChainId(p.ChainID)
-	if err != nil {
-		log.WithField("prefix", "main").Error(err)
-		return
-	}
-
-	aevmClient, err := store.EVM.GetClientByChainId(619001)
-	if err != nil {
-		log.WithField("prefix", "main").Error(err)
-		return
-	}
-
 	for {
 
 		select {
@@ -124,13 +288,34 @@ func getBridge(p config.Bridge, die chan bool) {
 
 			time.Sleep(time.Duration(timeout) * time.Second)
 
+			// make sure the cursor is still on the canonical chain
+			ancestor, reorged, err := checkReorg(r)
+			if err != nil {
+				log.WithField("prefix", "main").Error("Error verifying cursor on chain ", r.chainID, ": ", err)
+				timeout = 30
+				continue
+			}
+			if reorged {
+				tripBreaker(bridgeScope(r.bridge.Address), fmt.Sprintf("reorg on chain %d below block %d", r.chainID, ancestor))
+				firstBlock = ancestor
+				timeout = 3
+				continue
+			}
+
 			// lastBlock always = first + LIMIT
 			lastBlock = firstBlock + evm.EVM_EVENTS_LIMIT
 
-			// check current evm block, if last block > current, use current as last instead
-			currentBlock, err := client.GetCurrentBlockNumber()
+			// check confirmed evm block, if last block > confirmed, use confirmed as last instead
+			currentBlock, err := r.source.GetConfirmedBlockNumber(r.confirmations)
 			if err != nil {
-				log.WithField("prefix", "main").Error("Error fetching current block number: ", err)
+				log.WithField("prefix", "main").Error("Error fetching confirmed block number: ", err)
+				timeout = 30
+				continue
+			}
+
+			// nothing confirmed above the cursor yet
+			if currentBlock < firstBlock {
+				log.Debug("No confirmed blocks on chain ", r.chainID, " above ", firstBlock)
 				timeout = 30
 				continue
 			}
@@ -140,31 +325,80 @@ func getBridge(p config.Bridge, die chan bool) {
 				lastBlock = currentBlock
 			}
 
-			log.Info("Parsing events on chain=", p.ChainID, " from ", firstBlock, " to ", lastBlock)
+			log.Info("Parsing events on chain=", r.chainID, " from ", firstBlock, " to ", lastBlock)
 
-			evmEvents, err := client.GetDepositEvents(p.Address, firstBlock, &lastBlock)
+			evmEvents, err := r.getEvents(firstBlock, &lastBlock)
 			if err != nil {
 				log.WithField("prefix", "main").Error(err)
 				timeout = 30
 				continue
 			}
 
-			log.Debug("Found ", len(evmEvents), " deposit events on chain ", p.ChainID)
+			log.Debug("Found ", len(evmEvents), " ", r.direction, " events on chain ", r.chainID)
+
+			lastBlockHash, err := r.source.GetBlockHash(lastBlock)
+			if err != nil {
+				log.WithField("prefix", "main").Error(err)
+				timeout = 30
+				continue
+			}
+
+			// the endpoint must agree with independent nodes, otherwise it may serve a fake chain
+			if err := r.source.VerifyBlockHash(lastBlock, lastBlockHash); errors.Is(err, evm.ErrRPCDisagreement) {
+				tripBreaker(chainScope(r.chainID), err.Error())
+				timeout = 30
+				continue
+			} else if err != nil {
+				log.WithField("prefix", "main").Warn(err)
+			}
+
+			// the bridge owner can change the minted token, transfers to anyone but the expected owner need operator review
+			if r.direction == DIRECTION_DEPOSIT {
+				transfers, err := r.source.GetOwnershipTransfers(r.bridge.Address, firstBlock, &lastBlock)
+				if err != nil {
+					log.WithField("prefix", "main").Error(err)
+					timeout = 30
+					continue
+				}
+				for _, t := range transfers {
+					if r.transfers[t.TxHash] || strings.EqualFold(t.NewOwner, r.bridge.ExpectedOwner) {
+						continue
+					}
+					r.transfers[t.TxHash] = true
+					tripBreaker(bridgeScope(r.bridge.Address), fmt.Sprintf("ownership transferred from %s to %s in tx %s", t.PreviousOwner, t.NewOwner, t.TxHash))
+				}
+			}
 
-			if len(evmEvents) > 0 {
-				for _, event := range evmEvents {
-					log.Info("Minting ", event.Amount, " of token=", p.RebaseToken, " to ", event.Receiver)
-					legacyTx, signature, err := aevmClient.GenerateAndSignERC20Mint(common.HexToAddress(p.RebaseToken), common.HexToAddress(event.Receiver), event.Amount)
+			// record every event in the ledger before anything is executed
+			recorded := true
+			for _, event := range evmEvents {
+				for _, deposit := range r.newDeposits(event) {
+					_, created, err := store.Ledger.AddDeposit(deposit)
 					if err != nil {
-						log.Error(err)
+						log.WithField("prefix", "main").Error("failed to record ", r.direction, " ", deposit.ID(), ": ", err)
+						recorded = false
+						break
+					}
+					if !created {
+						log.Debug(r.direction, " ", deposit.ID(), " already in ledger")
+						continue
+					}
+					if deposit.Dust.Sign() > 0 {
+						log.WithField("prefix", "main").Warn(deposit.ID(), " of ", deposit.Amount, " leaves dust of ", deposit.Dust, " below ", r.toDecimals, " decimals")
 					}
-					tx := aevmClient.PrepareTx(legacyTx)
-					txhash, err := aevmClient.SubmitTx(tx, signature)
-					log.Info("Tx sent: ", txhash.Hex())
+				}
+				if !recorded {
+					break
 				}
 			}
 
-			store.Data.AddBlock(lastBlock, p.ChainID, p.Address)
+			// do not move the cursor past events missing from the ledger
+			if !recorded {
+				timeout = 30
+				continue
+			}
+
+			store.Data.AddBlock(lastBlock, lastBlockHash, r.chainID, r.cursor)
 			firstBlock = lastBlock
 
 			timeout = 30
@@ -181,3 +415,56 @@ func getBridge(p config.Bridge, die chan bool) {
 	}
 
 }
+
+// checkReorg verifies the route cursor against the canonical chain.
+// On mismatch it walks back the checkpoints to the common ancestor, flags deposits from orphaned blocks and rewinds the cursor.
+func checkReorg(r *route) (uint64, bool, error) {
+
+	checkpoints := store.Data.GetCheckpoints(r.chainID, r.cursor)
+	if len(checkpoints) == 0 {
+		return 0, false, nil
+	}
+
+	last := checkpoints[len(checkpoints)-1]
+	hash, err := r.source.GetBlockHash(last.Number)
+	if err != nil {
+		return 0, false, err
+	}
+	if strings.EqualFold(hash, last.Hash) {
+		return 0, false, nil
+	}
+
+	log.WithField("prefix", "main").Warn("Reorg detected on chain ", r.chainID, ": block ", last.Number, " changed from ", last.Hash, " to ", hash)
+
+	// if no checkpoint survived, rescan from the configured block, the ledger keeps it idempotent
+	ancestor := r.startBlock
+	found := false
+	for i := len(checkpoints) - 2; i >= 0; i-- {
+		hash, err := r.source.GetBlockHash(checkpoints[i].Number)
+		if err != nil {
+			return 0, false, err
+		}
+		if strings.EqualFold(hash, checkpoints[i].Hash) {
+			ancestor = checkpoints[i].Number
+			found = true
+			break
+		}
+	}
+	if !found {
+		log.WithField("prefix", "main").Error("No common ancestor found in ", len(checkpoints), " checkpoints on chain ", r.chainID, ", rescanning from block ", ancestor)
+	}
+
+	minted, err := store.Ledger.FlagOrphaned(r.chainID, r.address, r.target.ChainID, ancestor)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, d := range minted {
+		log.WithField("prefix", "main").Error("Deposit ", d.ID(), " executed in tx ", d.MintTxHash, " is from an orphaned block, needs operator review")
+	}
+
+	store.Data.RewindBlock(ancestor, r.chainID, r.cursor)
+
+	log.WithField("prefix", "main").Info("Rewound cursor on chain ", r.chainID, " to block ", ancestor)
+
+	return ancestor, true, nil
+}
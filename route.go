@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/AccumulatedFinance/aevm-bridge/binding"
+	"github.com/AccumulatedFinance/aevm-bridge/config"
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const DIRECTION_DEPOSIT = "deposit"
+const DIRECTION_WITHDRAWAL = "withdrawal"
+
+// route is one direction of a bridge: events scanned on the source client are executed on the target client
+type route struct {
+	bridge        config.Bridge
+	direction     string
+	chainID       int    // scanned chain
+	address       string // scanned contract, keys the ledger
+	cursor        string // keys the scan cursor, unique per route
+	startBlock    uint64
+	confirmations uint64
+	source        *evm.EVMClient
+	target        *evm.EVMClient
+	fromDecimals  uint8 // decimals of scanned amounts
+	toDecimals    uint8 // decimals of executed amounts
+	fees          *feePolicy
+	caps          *mintCaps
+	threshold     *big.Int           // larger executions wait for operator approval, nil if not configured
+	transfers     map[string]bool    // ownership transfers already reported, scanned ranges overlap by a block
+	safe          *safeProposer      // mints are proposed to the Safe of the bridge, nil if they are sent directly
+	withdrawals   *evm.WithdrawalABI // Redeem event and release method of withdrawal routes
+}
+
+// newDepositRoute scans Deposit events of the source chain bridge and mints on the destination chain
+func newDepositRoute(b config.Bridge) (*route, error) {
+
+	// the withdrawal route releases with the source client
+	source, err := store.EVM.GetBridgeClient(b.ChainID, b.Address)
+	if err != nil {
+		return nil, fmt.Errorf("source %w", err)
+	}
+
+	target, err := store.EVM.GetBridgeClient(b.DestinationChainID, b.Address)
+	if err != nil {
+		return nil, fmt.Errorf("destination %w", err)
+	}
+
+	// bridge confirmations override the network default
+	confirmations := source.Confirmations
+	if b.Confirmations > 0 {
+		confirmations = b.Confirmations
+	}
+
+	sourceDecimals, destinationDecimals, err := getDecimals(b, source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	fees, err := newFeePolicy(b)
+	if err != nil {
+		return nil, err
+	}
+
+	caps, err := newMintCaps(b)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold, err := parseCap("approvalThreshold", b.ApprovalThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// reverts of mints are decoded with the errors of the minter
+	minterABI := b.MinterABI
+	if minterABI == "" {
+		minterABI = evm.ERC20_ERRORS_ABI
+	}
+	if err := evm.RegisterErrors(b.DestinationChainID, common.HexToAddress(b.GetMinter()), minterABI); err != nil {
+		return nil, fmt.Errorf("minterABI: %w", err)
+	}
+
+	return &route{
+		bridge:        b,
+		direction:     DIRECTION_DEPOSIT,
+		chainID:       b.ChainID,
+		address:       b.Address,
+		cursor:        b.Address,
+		startBlock:    b.BlockNumber,
+		confirmations: confirmations,
+		source:        source,
+		target:        target,
+		fromDecimals:  sourceDecimals,
+		toDecimals:    destinationDecimals,
+		fees:          fees,
+		caps:          caps,
+		threshold:     threshold,
+		transfers:     make(map[string]bool),
+	}, nil
+
+}
+
+// newWithdrawalRoute scans Redeem events of the rebase token on the destination chain and releases on the source chain bridge
+func newWithdrawalRoute(b config.Bridge) (*route, error) {
+
+	deposits, err := newDepositRoute(b)
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals, err := evm.NewWithdrawalABI(b.RedeemABI, b.ReleaseABI)
+	if err != nil {
+		return nil, err
+	}
+
+	// reverts of releases are decoded with the errors of the bridge binding and of releaseABI
+	if err := evm.RegisterErrors(b.ChainID, common.HexToAddress(b.Address), binding.BridgeMetaData.ABI); err != nil {
+		return nil, err
+	}
+	if b.ReleaseABI != "" {
+		if err := evm.RegisterErrors(b.ChainID, common.HexToAddress(b.Address), b.ReleaseABI); err != nil {
+			return nil, fmt.Errorf("releaseABI: %w", err)
+		}
+	}
+
+	return &route{
+		bridge:        b,
+		direction:     DIRECTION_WITHDRAWAL,
+		chainID:       b.DestinationChainID,
+		address:       b.RebaseToken,
+		cursor:        fmt.Sprintf("%s:%d", b.RebaseToken, b.ChainID), // several bridges may share the rebase token
+		startBlock:    b.WithdrawalBlockNumber,
+		confirmations: deposits.target.Confirmations,
+		source:        deposits.target,
+		target:        deposits.source,
+		fromDecimals:  deposits.toDecimals,
+		toDecimals:    deposits.fromDecimals,
+		fees:          &feePolicy{fixed: big.NewInt(0), minDeposit: big.NewInt(0)}, // fees are charged on deposits only
+		caps:          deposits.caps,                                               // counted separately from mints, in rebase token units too
+		threshold:     deposits.threshold,                                          // compared with the redeemed amounts in rebase token units
+		withdrawals:   withdrawals,
+	}, nil
+
+}
+
+// getDecimals returns decimals of the bridge tokens, configured values win over the ones read from the contracts
+func getDecimals(b config.Bridge, source *evm.EVMClient, target *evm.EVMClient) (uint8, uint8, error) {
+
+	sourceDecimals := b.SourceDecimals
+	if sourceDecimals == 0 {
+		wst, err := source.GetBridgeWst(b.Address)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get wst token: %w", err)
+		}
+		if sourceDecimals, err = source.GetTokenDecimals(wst); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	destinationDecimals := b.DestinationDecimals
+	if destinationDecimals == 0 {
+		var err error
+		if destinationDecimals, err = target.GetTokenDecimals(common.HexToAddress(b.RebaseToken)); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return sourceDecimals, destinationDecimals, nil
+
+}
+
+// convert scales amount from scanned to executed token units, returning the unrepresentable dust in scanned units
+func (r *route) convert(amount *big.Int) (*big.Int, *big.Int) {
+	return evm.ScaleAmount(amount, r.fromDecimals, r.toDecimals)
+}
+
+// newDeposits returns ledger records of the event: the bridged amount after fees and, if a treasury is set, the fee mint
+func (r *route) newDeposits(event *evm.BridgeEvent) []*store.Deposit {
+
+	deposit := &store.Deposit{
+		ChainID:       r.chainID,
+		Address:       r.address,
+		Direction:     r.direction,
+		Bridge:        r.bridge.Address,
+		TargetChainID: r.target.ChainID,
+		TxHash:        event.TxHash,
+		LogIndex:      event.LogIndex,
+		BlockNumber:   event.BlockNumber,
+		BlockHash:     event.BlockHash,
+		Receiver:      event.Receiver,
+		Amount:        event.Amount,
+		Fee:           big.NewInt(0),
+	}
+
+	// deposits below the minimum are skipped without a fee
+	if r.fees.belowMinimum(event.Amount) {
+		deposit.MintAmount, deposit.Dust = r.convert(event.Amount)
+		return []*store.Deposit{deposit}
+	}
+
+	net, fee := r.fees.charge(event.Amount)
+	deposit.Fee = fee
+	deposit.MintAmount, deposit.Dust = r.convert(net)
+
+	if fee.Sign() == 0 || r.fees.treasury == "" {
+		return []*store.Deposit{deposit}
+	}
+
+	feeMint := *deposit
+	feeMint.Kind = store.DepositKindFee
+	feeMint.Receiver = r.fees.treasury
+	feeMint.Amount = fee
+	feeMint.Fee = big.NewInt(0)
+	feeMint.MintAmount, feeMint.Dust = r.convert(fee)
+
+	return []*store.Deposit{deposit, &feeMint}
+
+}
+
+// needsApproval returns true if the deposit is above the approval threshold and was not approved yet
+func (r *route) needsApproval(d *store.Deposit) bool {
+
+	if r.threshold == nil || d.Approved || d.Kind != "" {
+		return false
+	}
+
+	// the threshold is in rebase token units, minted by deposits and redeemed by withdrawals
+	amount := d.MintAmount
+	if r.direction == DIRECTION_WITHDRAWAL {
+		amount = d.Amount
+	}
+
+	return amount.Cmp(r.threshold) > 0
+
+}
+
+// getEvents retrieves events of the route from start to end blocks
+func (r *route) getEvents(start uint64, end *uint64) ([]*evm.BridgeEvent, error) {
+
+	if r.direction == DIRECTION_WITHDRAWAL {
+		return r.source.GetRedeemEvents(r.withdrawals, r.address, r.bridge.ChainID, r.bridge.Address, start, end)
+	}
+
+	return r.source.GetDepositEvents(r.address, start, end)
+
+}
+
+// buildCall builds the call executing the deposit on the target chain, with attestations in attestation mode
+func (r *route) buildCall(d *store.Deposit, signatures [][]byte) (ethereum.CallMsg, error) {
+
+	if signatures != nil {
+		message, contract := r.attestation(d)
+		if r.direction == DIRECTION_WITHDRAWAL {
+			return r.target.BuildAttestedRelease(contract, message, signatures)
+		}
+		return r.target.BuildAttestedMint(contract, message, signatures)
+	}
+
+	if r.direction == DIRECTION_WITHDRAWAL {
+		return r.target.BuildRelease(r.withdrawals, common.HexToAddress(r.bridge.Address), common.HexToAddress(d.Receiver), d.MintAmount)
+	}
+
+	return r.target.BuildERC20Mint(common.HexToAddress(r.bridge.GetMinter()), common.HexToAddress(d.Receiver), d.MintAmount)
+
+}
@@ -0,0 +1,769 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const SAFE_BATCH_SIZE = 20
+const SAFE_TIMEOUT = 10 * time.Second
+const SAFE_PROPOSAL_ORIGIN = "aevm-bridge"
+
+// safeProposer proposes mints of a bridge as Safe txs instead of sending them, the owners execute them
+type safeProposer struct {
+	address   common.Address
+	multiSend common.Address
+	service   string // Safe transaction service, proposals are written to dir if not set
+	dir       string
+	batchSize int
+	client    *http.Client
+}
+
+// safeProposal is a Safe tx signed by one owner, in the format of the Safe transaction service
+type safeProposal struct {
+	Safe                    string   `json:"safe"`
+	To                      string   `json:"to"`
+	Value                   string   `json:"value"`
+	Data                    string   `json:"data"`
+	Operation               uint8    `json:"operation"`
+	SafeTxGas               string   `json:"safeTxGas"`
+	BaseGas                 string   `json:"baseGas"`
+	GasPrice                string   `json:"gasPrice"`
+	GasToken                string   `json:"gasToken"`
+	RefundReceiver          string   `json:"refundReceiver"`
+	Nonce                   uint64   `json:"nonce"`
+	ContractTransactionHash string   `json:"contractTransactionHash"`
+	Sender                  string   `json:"sender"`
+	Signature               string   `json:"signature"`
+	Origin                  string   `json:"origin"`
+	Deposits                []string `json:"deposits"` // ledger IDs of the batched mints, for co-signers
+}
+
+// newSafeProposer checks that the route signer owns the Safe and re-queues mints reserved but never signed before a restart
+func newSafeProposer(dir string, r *route) (*safeProposer, error) {
+
+	conf := r.bridge.Safe
+	if !common.IsHexAddress(conf.Address) {
+		return nil, fmt.Errorf("invalid Safe address %s", conf.Address)
+	}
+
+	s := &safeProposer{
+		address:   common.HexToAddress(conf.Address),
+		multiSend: common.HexToAddress(evm.SAFE_MULTISEND_CALL_ONLY),
+		service:   strings.TrimRight(conf.Service, "/"),
+		batchSize: conf.BatchSize,
+		client:    &http.Client{Timeout: SAFE_TIMEOUT},
+	}
+	if conf.MultiSend != "" {
+		if !common.IsHexAddress(conf.MultiSend) {
+			return nil, fmt.Errorf("invalid MultiSend address %s", conf.MultiSend)
+		}
+		s.multiSend = common.HexToAddress(conf.MultiSend)
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = SAFE_BATCH_SIZE
+	}
+	if s.service == "" {
+		if conf.Directory == "" {
+			return nil, errors.New("Safe service or directory must be set")
+		}
+		s.dir = resolvePath(dir, conf.Directory)
+	}
+
+	owner, err := r.target.IsSafeOwner(s.address, r.target.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !owner {
+		return nil, fmt.Errorf("signer %s is not an owner of Safe %s", r.target.PublicKey.Hex(), s.address.Hex())
+	}
+
+	for _, d := range s.proposed(r.target.ChainID) {
+		if d.SafeTxHash != "" {
+			continue
+		}
+		if _, err := store.Ledger.UpdateDeposit(d, store.DepositProposed, func(d *store.Deposit) {
+			d.Status = store.DepositPending
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	log.WithField("prefix", "safe").Info("Proposing mints of bridge ", r.bridge.Address, " to Safe ", s.address.Hex(), " as owner ", r.target.PublicKey.Hex())
+
+	return s, nil
+
+}
+
+// proposed returns proposed mints of the Safe
+func (s *safeProposer) proposed(chainID int) []*store.Deposit {
+
+	var deposits []*store.Deposit
+	for _, d := range store.Ledger.GetDepositsByStatus(store.DepositProposed) {
+		if d.MintChainID == chainID && strings.EqualFold(d.MintFrom, s.address.Hex()) {
+			deposits = append(deposits, d)
+		}
+	}
+
+	return deposits
+
+}
+
+// cursor keys the scan of Safe executions in the data store
+func (s *safeProposer) cursor() string {
+	return "safe:" + s.address.Hex()
+}
+
+// proposeDeposits proposes the mints in Safe txs of up to batchSize mints each
+func proposeDeposits(r *route, deposits []*store.Deposit) {
+
+	for len(deposits) > 0 {
+
+		batch := deposits
+		if len(batch) > r.safe.batchSize {
+			batch = batch[:r.safe.batchSize]
+		}
+		deposits = deposits[len(batch):]
+
+		// the cap was handled by reserve
+		err := r.safe.propose(r, batch)
+		if errors.Is(err, ErrCapReached) {
+			return
+		}
+		if err != nil {
+			log.WithField("prefix", "safe").Error("failed to propose mints of bridge ", r.bridge.Address, ": ", err)
+			return
+		}
+
+	}
+
+}
+
+// propose reserves the mints within the caps, signs them as one Safe tx and publishes it,
+// mints after the first one over a cap are left pending
+func (s *safeProposer) propose(r *route, batch []*store.Deposit) error {
+
+	// executions are scanned from before the first proposal
+	if _, err := store.Data.GetBlock(r.target.ChainID, s.cursor()); err != nil {
+		current, err := r.target.GetCurrentBlockNumber()
+		if err != nil {
+			return err
+		}
+		store.Data.AddBlock(current, "", r.target.ChainID, s.cursor())
+	}
+
+	reserved, stopErr := s.reserve(r, batch)
+	if len(reserved) == 0 {
+		return stopErr
+	}
+
+	reserved, calls, err := s.simulate(r, reserved)
+	if err != nil {
+		s.release(reserved, err)
+		return err
+	}
+	if len(reserved) == 0 {
+		return stopErr
+	}
+
+	proposal, err := s.sign(r, reserved, calls)
+	if err != nil {
+		s.release(reserved, err)
+		return err
+	}
+
+	// the service may have stored the proposal anyway, track verifies it before the mints are released
+	if err := s.publish(proposal); err != nil {
+		for _, deposit := range reserved {
+			if _, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+				d.SafeUnpublished = true
+				d.Error = err.Error()
+			}); err != nil {
+				log.WithField("prefix", "safe").Error(err)
+			}
+		}
+		return err
+	}
+
+	log.WithField("prefix", "safe").Info("Proposed ", len(reserved), " mints to Safe ", s.address.Hex(), " with nonce ", proposal.Nonce, ": ", proposal.ContractTransactionHash)
+
+	return stopErr
+
+}
+
+// reserve records the mints as proposed one by one, so the caps include every mint of the batch
+func (s *safeProposer) reserve(r *route, batch []*store.Deposit) ([]*store.Deposit, error) {
+
+	capsMu.Lock()
+	defer capsMu.Unlock()
+
+	var reserved []*store.Deposit
+	for _, deposit := range batch {
+		err := r.checkCaps(deposit)
+		if errors.Is(err, ErrReceiverCapReached) {
+			capReached(r, deposit, err)
+			continue
+		}
+		if err != nil {
+			capReached(r, deposit, err)
+			return reserved, err
+		}
+		updated, err := store.Ledger.UpdateDeposit(deposit, deposit.Status, func(d *store.Deposit) {
+			d.Status = store.DepositProposed
+			d.MintChainID = r.target.ChainID
+			d.MintFrom = s.address.Hex()
+			d.SafeTxHash = ""
+			d.MintAmount = deposit.MintAmount
+			d.Dust = deposit.Dust
+			d.SubmittedAt = time.Now()
+			d.Error = ""
+		})
+		if errors.Is(err, store.ErrDepositChanged) {
+			log.WithField("prefix", "safe").Warn("Skipping ", deposit.ID(), ": ", err)
+			continue
+		}
+		if err != nil {
+			return reserved, err
+		}
+		reserved = append(reserved, updated)
+	}
+
+	return reserved, nil
+
+}
+
+// release puts reserved mints back to pending, they are proposed again with the next batch
+func (s *safeProposer) release(reserved []*store.Deposit, cause error) {
+
+	for _, deposit := range reserved {
+		if _, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+			d.Status = store.DepositPending
+			d.SafeTxHash = ""
+			d.Error = cause.Error()
+		}); err != nil {
+			log.WithField("prefix", "safe").Error(err)
+		}
+	}
+
+}
+
+// simulate runs every mint as a call of the Safe, mints that would revert are settled by settleReverted and left out of the batch,
+// the others are returned with their calls
+func (s *safeProposer) simulate(r *route, reserved []*store.Deposit) ([]*store.Deposit, []ethereum.CallMsg, error) {
+
+	var simulated []*store.Deposit
+	var calls []ethereum.CallMsg
+	for i, deposit := range reserved {
+
+		msg, err := r.buildCall(deposit, nil)
+		if err != nil {
+			return append(simulated, reserved[i:]...), nil, err
+		}
+		msg.From = s.address
+
+		_, err = r.target.EstimateTx(msg)
+		var revertErr *evm.RevertError
+		if errors.As(err, &revertErr) {
+			log.WithField("prefix", "safe").Error("ALERT: ", r.direction, " ", deposit.ID(), " would revert in Safe ", s.address.Hex(), ": ", revertErr.Reason)
+			recordRevert(bridgeScope(r.bridge.Address))
+			if err := settleReverted(deposit, revertErr); err != nil {
+				log.WithField("prefix", "safe").Error(err)
+			}
+			continue
+		}
+		if err != nil {
+			return append(simulated, reserved[i:]...), nil, err
+		}
+
+		simulated = append(simulated, deposit)
+		calls = append(calls, msg)
+	}
+
+	return simulated, calls, nil
+
+}
+
+// sign builds the Safe tx of the mints, batched with MultiSend if there are several, and signs it as owner
+func (s *safeProposer) sign(r *route, reserved []*store.Deposit, calls []ethereum.CallMsg) (*safeProposal, error) {
+
+	tx := &evm.SafeTx{
+		Safe:      s.address,
+		To:        *calls[0].To,
+		Value:     big.NewInt(0),
+		Data:      calls[0].Data,
+		Operation: evm.SAFE_OPERATION_CALL,
+	}
+	if len(calls) > 1 {
+		data, err := evm.EncodeMultiSend(calls)
+		if err != nil {
+			return nil, err
+		}
+		tx.To = s.multiSend
+		tx.Data = data
+		tx.Operation = evm.SAFE_OPERATION_DELEGATECALL
+	}
+
+	nonce, err := s.nextNonce(r.target)
+	if err != nil {
+		return nil, err
+	}
+	tx.Nonce = nonce
+
+	data := tx.TypedData(r.target.ChainID)
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := r.target.Signer.SignTypedData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// record the proposal, it is published after
+	var ids []string
+	for _, deposit := range reserved {
+		if _, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+			d.SafeTxHash = hexutil.Encode(hash)
+			d.MintNonce = nonce
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record Safe tx: %w", err)
+		}
+		ids = append(ids, deposit.ID())
+	}
+
+	return &safeProposal{
+		Safe:                    s.address.Hex(),
+		To:                      tx.To.Hex(),
+		Value:                   tx.Value.String(),
+		Data:                    hexutil.Encode(tx.Data),
+		Operation:               tx.Operation,
+		SafeTxGas:               "0",
+		BaseGas:                 "0",
+		GasPrice:                "0",
+		GasToken:                evm.ETH_ZERO_ADDRESS,
+		RefundReceiver:          evm.ETH_ZERO_ADDRESS,
+		Nonce:                   nonce,
+		ContractTransactionHash: hexutil.Encode(hash),
+		Sender:                  r.target.PublicKey.Hex(),
+		Signature:               hexutil.Encode(signature),
+		Origin:                  SAFE_PROPOSAL_ORIGIN,
+		Deposits:                ids,
+	}, nil
+
+}
+
+// nextNonce returns the lowest Safe nonce from the on-chain nonce not used by pending proposals,
+// so nonces of released proposals are filled before later ones
+func (s *safeProposer) nextNonce(client *evm.EVMClient) (uint64, error) {
+
+	nonce, err := client.GetSafeNonce(s.address, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	used := make(map[uint64]bool)
+	for _, d := range s.proposed(client.ChainID) {
+		if d.SafeTxHash != "" {
+			used[d.MintNonce] = true
+		}
+	}
+	for used[nonce] {
+		nonce++
+	}
+
+	return nonce, nil
+
+}
+
+// publish sends the proposal to the Safe transaction service, or writes it to the proposals directory
+func (s *safeProposer) publish(p *safeProposal) error {
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if s.service != "" {
+		resp, err := s.client.Post(s.service+"/api/v1/safes/"+p.Safe+"/multisig-transactions/", "application/json", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return fmt.Errorf("Safe service returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".proposal.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.proposalFile(p.Nonce, p.ContractTransactionHash))
+
+}
+
+// proposalFile returns the path of a proposal in the proposals directory
+func (s *safeProposer) proposalFile(nonce uint64, safeTxHash string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%d-%s.json", s.address.Hex(), nonce, safeTxHash))
+}
+
+// published checks whether a proposal whose publishing failed reached the Safe service or the proposals directory
+func (s *safeProposer) published(nonce uint64, safeTxHash string) (bool, error) {
+
+	if s.service == "" {
+		_, err := os.Stat(s.proposalFile(nonce, safeTxHash))
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	resp, err := s.client.Get(s.service + "/api/v1/multisig-transactions/" + safeTxHash + "/")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return true, nil
+	default:
+		return false, fmt.Errorf("Safe service returned %s for %s", resp.Status, safeTxHash)
+	}
+
+}
+
+// duplicate returns a pending Safe tx in the service with the same call as the proposal of the deposit under another nonce,
+// nil if there is none or proposals are written to the proposals directory
+func (s *safeProposer) duplicate(chainID int, deposit *store.Deposit, nonce uint64) (*safeServiceTx, error) {
+
+	if s.service == "" {
+		return nil, nil
+	}
+
+	// txs of other proposals of the ledger are never taken over
+	tracked := make(map[string]bool)
+	for _, d := range s.proposed(chainID) {
+		tracked[strings.ToLower(d.SafeTxHash)] = true
+	}
+
+	next := fmt.Sprintf("%s/api/v1/safes/%s/multisig-transactions/?executed=false&nonce__gte=%d", s.service, s.address.Hex(), nonce)
+	for next != "" {
+
+		resp, err := s.client.Get(next)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Next    string           `json:"next"`
+			Results []*safeServiceTx `json:"results"`
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("Safe service returned %s for pending txs of %s", resp.Status, s.address.Hex())
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range page.Results {
+			if tracked[strings.ToLower(tx.SafeTxHash)] {
+				continue
+			}
+			// the same call signed under the nonce of the proposal hashes to the proposal
+			same, err := s.sameCall(chainID, tx, deposit)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				return tx, nil
+			}
+		}
+		next = page.Next
+
+	}
+
+	return nil, nil
+
+}
+
+// sameCall returns true if tx of the Safe service carries the call of the proposal of the deposit
+func (s *safeProposer) sameCall(chainID int, tx *safeServiceTx, deposit *store.Deposit) (bool, error) {
+
+	if !common.IsHexAddress(tx.To) {
+		return false, nil
+	}
+	value, ok := new(big.Int).SetString(tx.Value, 10)
+	if !ok {
+		return false, nil
+	}
+	var data []byte
+	if tx.Data != "" {
+		var err error
+		if data, err = hexutil.Decode(tx.Data); err != nil {
+			return false, nil
+		}
+	}
+
+	proposal := &evm.SafeTx{
+		Safe:      s.address,
+		To:        common.HexToAddress(tx.To),
+		Value:     value,
+		Data:      data,
+		Operation: tx.Operation,
+		Nonce:     deposit.MintNonce,
+	}
+	hash, _, err := apitypes.TypedDataAndHash(proposal.TypedData(chainID))
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(hexutil.Encode(hash), deposit.SafeTxHash), nil
+
+}
+
+// trackProposals scans Safe executions and moves proposed mints to mined or failed
+func trackProposals(routes []*route, die chan bool) {
+
+	for {
+
+		select {
+		default:
+
+			time.Sleep(TRACKER_INTERVAL)
+
+			// bridges may share a Safe
+			tracked := make(map[string]bool)
+			for _, r := range routes {
+				if r.safe == nil {
+					continue
+				}
+				key := fmt.Sprintf("%d:%s", r.target.ChainID, r.safe.address.Hex())
+				if tracked[key] {
+					continue
+				}
+				tracked[key] = true
+				if err := r.safe.track(r.target); err != nil {
+					log.WithField("prefix", "safe").Error("failed to track Safe ", r.safe.address.Hex(), " on chain ", r.target.ChainID, ": ", err)
+				}
+			}
+
+		case <-die:
+			return
+		}
+
+	}
+
+}
+
+// track resolves proposals executed up to the confirmed block, proposals whose nonce was used by another tx need operator review
+func (s *safeProposer) track(client *evm.EVMClient) error {
+
+	next, err := store.Data.GetBlock(client.ChainID, s.cursor())
+	if err != nil {
+		// nothing proposed yet
+		return nil
+	}
+	next++
+
+	end, err := client.GetConfirmedBlockNumber(client.Confirmations)
+	if err != nil {
+		return err
+	}
+	if end < next {
+		return nil
+	}
+
+	executions := make(map[string]*evm.SafeExecution)
+	for start := next; start <= end; start += evm.EVM_EVENTS_LIMIT + 1 {
+		last := start + evm.EVM_EVENTS_LIMIT
+		if last > end {
+			last = end
+		}
+		found, err := client.GetSafeExecutions(s.address, start, last)
+		if err != nil {
+			return err
+		}
+		for _, execution := range found {
+			executions[strings.ToLower(execution.SafeTxHash.Hex())] = execution
+		}
+	}
+
+	// the nonce at the scanned block tells which proposals can no longer be executed
+	nonce, err := client.GetSafeNonce(s.address, new(big.Int).SetUint64(end))
+	if err != nil {
+		return err
+	}
+
+	// proposals whose publishing failed, by Safe tx hash
+	published := make(map[string]*safeLookup)
+
+	for _, deposit := range s.proposed(client.ChainID) {
+
+		if deposit.SafeTxHash == "" {
+			continue
+		}
+
+		execution, executed := executions[strings.ToLower(deposit.SafeTxHash)]
+		switch {
+		case executed && execution.Success:
+			log.WithField("prefix", "safe").Info("Mint of deposit ", deposit.ID(), " executed by Safe ", s.address.Hex(), " in block ", execution.BlockNumber, ": ", execution.TxHash)
+			recordSuccess(depositScope(deposit))
+			if _, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+				d.Status = store.DepositMined
+				d.MintTxHash = execution.TxHash
+				d.MintBlock = execution.BlockNumber
+				d.Error = ""
+			}); err != nil {
+				return err
+			}
+		case executed:
+			log.WithField("prefix", "safe").Error("ALERT: mint of deposit ", deposit.ID(), " failed in Safe tx ", deposit.SafeTxHash, " executed in ", execution.TxHash)
+			recordRevert(depositScope(deposit))
+			if _, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+				d.Status = store.DepositFailed
+				d.MintTxHash = execution.TxHash
+				d.MintBlock = execution.BlockNumber
+				d.Error = "Safe tx execution failed"
+			}); err != nil {
+				return err
+			}
+		case deposit.MintNonce < nonce:
+			// another tx took the nonce, the owners rejected the proposal or the execution was not scanned
+			log.WithField("prefix", "safe").Error("ALERT: Safe nonce ", deposit.MintNonce, " of deposit ", deposit.ID(), " was used by another tx, needs operator review")
+			if _, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+				d.Status = store.DepositFailed
+				d.Error = fmt.Sprintf("Safe nonce %d used by another tx", deposit.MintNonce)
+			}); err != nil {
+				return err
+			}
+		case deposit.SafeUnpublished:
+			if err := s.verify(client.ChainID, deposit, nonce, published); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	store.Data.AddBlock(end, "", client.ChainID, s.cursor())
+
+	return nil
+
+}
+
+// safeLookup is what the Safe service knows of a proposal whose publishing failed
+type safeLookup struct {
+	found     bool
+	duplicate *safeServiceTx // pending Safe tx of the same mints under another nonce
+}
+
+// safeServiceTx is a multisig tx as listed by the Safe transaction service
+type safeServiceTx struct {
+	SafeTxHash string      `json:"safeTxHash"`
+	To         string      `json:"to"`
+	Value      string      `json:"value"`
+	Data       string      `json:"data"`
+	Operation  uint8       `json:"operation"`
+	Nonce      json.Number `json:"nonce"`
+}
+
+// verify resolves a proposal whose publishing failed: mints of a proposal found published are kept,
+// mints found in a pending Safe tx under another nonce follow that tx,
+// mints of a proposal that never left the relayer are released and proposed again
+func (s *safeProposer) verify(chainID int, deposit *store.Deposit, nonce uint64, published map[string]*safeLookup) error {
+
+	lookup, checked := published[deposit.SafeTxHash]
+	if !checked {
+		lookup = &safeLookup{}
+		var err error
+		if lookup.found, err = s.published(deposit.MintNonce, deposit.SafeTxHash); err != nil {
+			return err
+		}
+		if !lookup.found {
+			if lookup.duplicate, err = s.duplicate(chainID, deposit, nonce); err != nil {
+				return err
+			}
+		}
+		published[deposit.SafeTxHash] = lookup
+	}
+
+	if lookup.found {
+		log.WithField("prefix", "safe").Info("Safe tx ", deposit.SafeTxHash, " of deposit ", deposit.ID(), " was published")
+		_, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+			d.SafeUnpublished = false
+			d.Error = ""
+		})
+		return err
+	}
+
+	// proposing again would mint twice once both txs are executed
+	if lookup.duplicate != nil {
+		duplicateNonce, err := strconv.ParseUint(lookup.duplicate.Nonce.String(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid nonce of Safe tx %s: %w", lookup.duplicate.SafeTxHash, err)
+		}
+		log.WithField("prefix", "safe").Warn("Safe tx ", deposit.SafeTxHash, " of deposit ", deposit.ID(), " was never published, its mints are pending in Safe tx ", lookup.duplicate.SafeTxHash, " with nonce ", duplicateNonce)
+		_, err = store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+			d.SafeTxHash = lookup.duplicate.SafeTxHash
+			d.MintNonce = duplicateNonce
+			d.SafeUnpublished = false
+			d.Error = ""
+		})
+		return err
+	}
+
+	log.WithField("prefix", "safe").Warn("Safe tx ", deposit.SafeTxHash, " of deposit ", deposit.ID(), " was never published, proposing again")
+	_, err := store.Ledger.UpdateDeposit(deposit, store.DepositProposed, func(d *store.Deposit) {
+		d.Status = store.DepositPending
+		d.SafeTxHash = ""
+		d.SafeUnpublished = false
+	})
+	return err
+
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+func TestSafeDuplicate(t *testing.T) {
+
+	const chainID = 619001
+	safe := common.HexToAddress("0x0000000000000000000000000000000000005afe")
+	minter := common.HexToAddress("0x000000000000000000000000000000000000000A")
+	mints := []byte{1, 2, 3}
+
+	hash := func(data []byte, nonce uint64) string {
+		tx := &evm.SafeTx{Safe: safe, To: minter, Value: big.NewInt(0), Data: data, Operation: evm.SAFE_OPERATION_CALL, Nonce: nonce}
+		h, _, err := apitypes.TypedDataAndHash(tx.TypedData(chainID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hexutil.Encode(h)
+	}
+	listed := func(data []byte, nonce uint64) *safeServiceTx {
+		return &safeServiceTx{
+			SafeTxHash: hash(data, nonce),
+			To:         minter.Hex(),
+			Value:      "0",
+			Data:       hexutil.Encode(data),
+			Operation:  evm.SAFE_OPERATION_CALL,
+			Nonce:      json.Number(fmt.Sprint(nonce)),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pages   [][]*safeServiceTx
+		tracked string // Safe tx hash of another proposal in the ledger
+		want    string
+	}{
+		{name: "none pending", pages: [][]*safeServiceTx{{listed([]byte{9}, 6)}}},
+		{name: "same mints under another nonce", pages: [][]*safeServiceTx{{listed([]byte{9}, 6)}, {listed(mints, 7)}}, want: hash(mints, 7)},
+		{name: "tracked by another proposal", pages: [][]*safeServiceTx{{listed(mints, 7)}}, tracked: hash(mints, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ledger, err := store.NewLedgerStore(filepath.Join(t.TempDir(), "ledger.gob"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			previous := store.Ledger
+			store.Ledger = ledger
+			t.Cleanup(func() { store.Ledger = previous })
+
+			propose := func(logIndex uint, safeTxHash string, nonce uint64) *store.Deposit {
+				added, _, err := ledger.AddDeposit(&store.Deposit{
+					ChainID:   1,
+					Address:   minter.Hex(),
+					Direction: DIRECTION_DEPOSIT,
+					TxHash:    "0x00000000000000000000000000000000000000000000000000000000000000aa",
+					LogIndex:  logIndex,
+					Amount:    big.NewInt(1),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				proposed, err := ledger.UpdateDeposit(added, store.DepositPending, func(d *store.Deposit) {
+					d.Status = store.DepositProposed
+					d.MintChainID = chainID
+					d.MintFrom = safe.Hex()
+					d.SafeTxHash = safeTxHash
+					d.MintNonce = nonce
+					d.SafeUnpublished = true
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return proposed
+			}
+			deposit := propose(1, hash(mints, 5), 5)
+			if tt.tracked != "" {
+				propose(2, tt.tracked, 7)
+			}
+
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != "/api/v1/safes/"+safe.Hex()+"/multisig-transactions/" || req.URL.Query().Get("executed") != "false" {
+					http.NotFound(w, req)
+					return
+				}
+				page := 0
+				fmt.Sscan(req.URL.Query().Get("page"), &page)
+				next := ""
+				if page+1 < len(tt.pages) {
+					next = fmt.Sprintf("%s%s?executed=false&page=%d", server.URL, req.URL.Path, page+1)
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"next": next, "results": tt.pages[page]})
+			}))
+			defer server.Close()
+
+			s := &safeProposer{address: safe, service: server.URL, client: server.Client()}
+			duplicate, err := s.duplicate(chainID, deposit, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if duplicate != nil {
+				got = duplicate.SafeTxHash
+			}
+			if got != tt.want {
+				t.Errorf("duplicate = %q, want %q", got, tt.want)
+			}
+
+		})
+	}
+
+}
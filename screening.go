@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+)
+
+const SCREENING_RELOAD_INTERVAL = 30 * time.Second
+
+// SCREENING_MAX_REMOVED is the share of entries a reload of the denylist may remove, a truncated file must not shrink it
+const SCREENING_MAX_REMOVED = 0.5
+
+// denylist and allowlist screen receivers before minting, nil if not configured
+var denylist *addressList
+var allowlist *addressList
+
+// addressList is a file of addresses reloaded when it changes, one address per line, # starts a comment
+// the file must be replaced atomically, e.g. written to a temporary file and renamed over it
+type addressList struct {
+	mu        sync.RWMutex
+	file      string
+	guarded   bool // reloads emptying or shrinking the list are refused, a shorter denylist lets receivers through
+	modTime   time.Time
+	size      int64
+	addresses map[common.Address]bool
+}
+
+// newAddressList loads the list file, relative paths are resolved against dir
+func newAddressList(dir string, file string, guarded bool) (*addressList, error) {
+
+	if file == "" {
+		return nil, nil
+	}
+
+	l := &addressList{file: resolvePath(dir, file), guarded: guarded}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+
+}
+
+// reload reads the file if it was modified, a broken file or one removing most entries of a guarded list keeps the previous list
+func (l *addressList) reload() error {
+
+	info, err := os.Stat(l.file)
+	if err != nil {
+		return err
+	}
+
+	l.mu.RLock()
+	unchanged := info.ModTime().Equal(l.modTime) && info.Size() == l.size
+	previous, loaded := len(l.addresses), l.addresses != nil
+	l.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	file, err := os.Open(l.file)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	addresses := make(map[common.Address]bool)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if entry == "" {
+			continue
+		}
+		if !common.IsHexAddress(entry) {
+			return fmt.Errorf("%s:%d: invalid address %s", l.file, line, entry)
+		}
+		addresses[common.HexToAddress(entry)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// the first load takes the file as it is, restart to load a list that shrank on purpose
+	if loaded && l.guarded {
+		if len(addresses) == 0 && previous > 0 {
+			return fmt.Errorf("%s is empty, restart to clear the list", l.file)
+		}
+		if removed := previous - len(addresses); float64(removed) > float64(previous)*SCREENING_MAX_REMOVED {
+			return fmt.Errorf("%s removes %d of %d addresses, restart to load it", l.file, removed, previous)
+		}
+	}
+
+	l.mu.Lock()
+	l.addresses = addresses
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+	l.mu.Unlock()
+
+	log.WithField("prefix", "screening").Info("Loaded ", len(addresses), " addresses from ", l.file)
+
+	return nil
+
+}
+
+// contains returns true if the address is on the list
+func (l *addressList) contains(address string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.addresses[common.HexToAddress(address)]
+}
+
+// screenReceiver returns the reason to block the receiver, empty if it may be minted to
+func screenReceiver(receiver string) string {
+
+	if denylist != nil && denylist.contains(receiver) {
+		return "receiver " + receiver + " is on the denylist"
+	}
+
+	if allowlist != nil && !allowlist.contains(receiver) {
+		return "receiver " + receiver + " is not on the allowlist"
+	}
+
+	return ""
+
+}
+
+// reloadLists picks up changes of the list files
+func reloadLists(die chan bool) {
+
+	for {
+
+		select {
+		default:
+
+			time.Sleep(SCREENING_RELOAD_INTERVAL)
+
+			for _, l := range []*addressList{denylist, allowlist} {
+				if l == nil {
+					continue
+				}
+				if err := l.reload(); err != nil {
+					log.WithField("prefix", "screening").Error("failed to reload ", l.file, ", keeping the previous list: ", err)
+				}
+			}
+
+		case <-die:
+			return
+		}
+
+	}
+
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetDepositByID returns the deposit by its ID
+func (st *LedgerStore) GetDepositByID(id string) (*Deposit, error) {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	d, err := st.findByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.copy(), nil
+}
+
+// findByID looks up the deposit by its ID, the caller must hold the lock
+func (st *LedgerStore) findByID(id string) (*Deposit, error) {
+
+	for _, d := range st.deposits {
+		if strings.EqualFold(d.ID(), id) {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("deposit %s not found", id)
+}
+
+// HoldDeposit moves a pending, proposed or submitted deposit to the approval queue, unless it changed since d was read
+func (st *LedgerStore) HoldDeposit(d *Deposit, reason string) (*Deposit, error) {
+	return st.UpdateDeposit(d, d.Status, func(d *Deposit) {
+		d.Status = DepositAwaitingApproval
+		d.Error = reason
+	})
+}
+
+// ApproveDeposit releases a deposit from the approval queue, it is executed as pending unless it is held for another reason.
+// The txs of a held mint whose nonce was taken are forgotten, so no receipt of them is credited to the new mint.
+func (st *LedgerStore) ApproveDeposit(id string, operator string) (*Deposit, error) {
+	return st.review(id, operator, func(d *Deposit) {
+		d.Status = DepositPending
+		d.Approved = true
+		d.ApprovedReason = d.Error
+		d.Error = ""
+		d.MintTxHash = ""
+		d.MintNonce = 0
+		d.MintRawTx = nil
+		d.ReplacedTxs = nil
+		d.ReplacedAt = time.Time{}
+	})
+}
+
+// RejectDeposit removes a deposit from the approval queue for good, together with its pending fee mint
+func (st *LedgerStore) RejectDeposit(id string, operator string, reason string) (*Deposit, error) {
+	return st.review(id, operator, func(d *Deposit) {
+		d.Status = DepositRejected
+		d.Error = reason
+	})
+}
+
+// review applies the decision of the operator to a deposit awaiting approval and to its pending fee mint
+func (st *LedgerStore) review(id string, operator string, decide func(d *Deposit)) (*Deposit, error) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	existing, err := st.findByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status != DepositAwaitingApproval {
+		return nil, fmt.Errorf("deposit %s is %s, not awaiting approval", id, existing.Status)
+	}
+
+	now := time.Now()
+	previous := map[ledgerKey]*Deposit{existing.key(): existing}
+	var changed []*Deposit
+
+	reviewed := existing.copy()
+	decide(reviewed)
+	reviewed.ReviewedBy = operator
+	reviewed.ReviewedAt = now
+	reviewed.UpdatedAt = now
+	st.deposits[existing.key()] = reviewed
+	changed = append(changed, reviewed)
+
+	// a rejected deposit takes its fee with it, an approved one leaves the fee waiting for the deposit mint
+	if existing.Kind == "" && reviewed.Status == DepositRejected {
+		feeKey := existing.key()
+		feeKey.Kind = DepositKindFee
+		if fee, exists := st.deposits[feeKey]; exists && fee.Status == DepositPending {
+			previous[feeKey] = fee
+			rejected := fee.copy()
+			rejected.Status = DepositRejected
+			rejected.Error = "deposit " + existing.ID() + " rejected"
+			rejected.ReviewedBy = operator
+			rejected.ReviewedAt = now
+			rejected.UpdatedAt = now
+			st.deposits[feeKey] = rejected
+			changed = append(changed, rejected)
+		}
+	}
+
+	if err := st.write(changed...); err != nil {
+		for k, d := range previous {
+			st.deposits[k] = d
+		}
+		return nil, err
+	}
+
+	return reviewed.copy(), nil
+}
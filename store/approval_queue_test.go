@@ -0,0 +1,79 @@
+package store
+
+import "testing"
+
+func TestReviewDeposit(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		status  DepositStatus // held from
+		approve bool
+		want    DepositStatus
+		wantFee DepositStatus
+	}{
+		{name: "approve", status: DepositPending, approve: true, want: DepositPending, wantFee: DepositPending},
+		{name: "reject", status: DepositPending, approve: false, want: DepositRejected, wantFee: DepositRejected},
+		{name: "approve a mint whose nonce was taken", status: DepositSubmitted, approve: true, want: DepositPending, wantFee: DepositPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			st, _ := newTestLedger(t)
+			d := addDeposit(t, st, newDeposit(1), tt.status)
+			if tt.status == DepositSubmitted {
+				var err error
+				d, err = st.UpdateDeposit(d, DepositSubmitted, func(d *Deposit) {
+					d.MintTxHash = "0x01"
+					d.MintNonce = 7
+					d.MintRawTx = []byte{1}
+					d.ReplacedTxs = []string{"0x02"}
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			fee := newDeposit(1)
+			fee.Kind = DepositKindFee
+			addDeposit(t, st, fee, DepositPending)
+
+			if _, err := st.HoldDeposit(d, "amount above approval threshold"); err != nil {
+				t.Fatal(err)
+			}
+
+			var err error
+			if tt.approve {
+				d, err = st.ApproveDeposit(d.ID(), "alice")
+			} else {
+				d, err = st.RejectDeposit(d.ID(), "alice", "no")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Status != tt.want || d.ReviewedBy != "alice" {
+				t.Errorf("status = %s reviewed by %q, want %s by alice", d.Status, d.ReviewedBy, tt.want)
+			}
+			if tt.approve && (!d.Approved || d.ApprovedReason != "amount above approval threshold") {
+				t.Errorf("approved=%v reason=%q", d.Approved, d.ApprovedReason)
+			}
+			if tt.approve && (d.MintTxHash != "" || d.MintNonce != 0 || d.MintRawTx != nil || d.ReplacedTxs != nil) {
+				t.Errorf("approved with the txs of the held mint: %s nonce %d replaced %v", d.MintTxHash, d.MintNonce, d.ReplacedTxs)
+			}
+
+			stored, err := st.GetDepositByID(fee.ID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.wantFee {
+				t.Errorf("fee status = %s, want %s", stored.Status, tt.wantFee)
+			}
+
+			// a reviewed deposit is no longer in the queue
+			if _, err := st.ApproveDeposit(d.ID(), "bob"); err == nil {
+				t.Error("reviewed twice")
+			}
+
+		})
+	}
+
+}
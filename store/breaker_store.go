@@ -0,0 +1,150 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var Breaker *BreakerStore
+
+// BREAKER_SCOPE_GLOBAL pauses every bridge
+const BREAKER_SCOPE_GLOBAL = "global"
+
+// Pause is a tripped circuit breaker, it stays until an operator resumes the scope
+type Pause struct {
+	Scope    string // global, bridge:<address> or chain:<chainID>
+	Reason   string
+	PausedAt time.Time
+}
+
+// BreakerStore is the persistent state of circuit breakers, every change is flushed to disk before it is visible
+type BreakerStore struct {
+	mu     sync.RWMutex
+	file   string
+	pauses map[string]*Pause
+}
+
+// NewBreakerStore opens the breaker file, a missing file starts with nothing paused
+func NewBreakerStore(file string) (*BreakerStore, error) {
+
+	st := &BreakerStore{
+		file:   file,
+		pauses: make(map[string]*Pause),
+	}
+
+	log.WithField("prefix", "store").Debug("reading breaker file: ", file)
+	if err := st.read(); err != nil {
+		return nil, fmt.Errorf("failed to read breaker %s: %w", file, err)
+	}
+
+	return st, nil
+
+}
+
+// Trip pauses the scope, tripped is false if it was already paused.
+// A pause that fails to persist still holds until an operator resumes the scope, it is only lost on restart.
+func (st *BreakerStore) Trip(scope string, reason string) (tripped bool, err error) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.pauses[scope]; exists {
+		return false, nil
+	}
+
+	st.pauses[scope] = &Pause{
+		Scope:    scope,
+		Reason:   reason,
+		PausedAt: time.Now(),
+	}
+	if err := st.write(); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// Resume lifts the pause of the scope
+func (st *BreakerStore) Resume(scope string) (*Pause, error) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	pause, exists := st.pauses[scope]
+	if !exists {
+		return nil, fmt.Errorf("%s is not paused", scope)
+	}
+
+	delete(st.pauses, scope)
+	if err := st.write(); err != nil {
+		st.pauses[scope] = pause
+		return nil, err
+	}
+
+	return pause, nil
+}
+
+// Paused returns the first pause among the scopes, nil if none of them is paused
+func (st *BreakerStore) Paused(scopes ...string) *Pause {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	for _, scope := range scopes {
+		if pause, exists := st.pauses[scope]; exists {
+			p := *pause
+			return &p
+		}
+	}
+
+	return nil
+}
+
+// GetPauses returns all pauses ordered by time
+func (st *BreakerStore) GetPauses() []*Pause {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	pauses := make([]*Pause, 0, len(st.pauses))
+	for _, pause := range st.pauses {
+		p := *pause
+		pauses = append(pauses, &p)
+	}
+
+	sort.Slice(pauses, func(i, j int) bool {
+		return pauses[i].PausedAt.Before(pauses[j].PausedAt)
+	})
+
+	return pauses
+}
+
+// write persists pauses atomically, the caller must hold the lock
+func (st *BreakerStore) write() error {
+
+	pauses := make([]*Pause, 0, len(st.pauses))
+	for _, pause := range st.pauses {
+		pauses = append(pauses, pause)
+	}
+
+	return writeGob(st.file, pauses)
+}
+
+// read loads pauses from the filesystem, the caller must hold the lock
+func (st *BreakerStore) read() error {
+
+	var pauses []*Pause
+	if _, err := readGob(st.file, &pauses); err != nil {
+		return err
+	}
+
+	for _, pause := range pauses {
+		st.pauses[pause.Scope] = pause
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBreakerStore(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "breaker.gob")
+	st, err := NewBreakerStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scope   string
+		tripped bool
+	}{
+		{BREAKER_SCOPE_GLOBAL, true},
+		{"bridge:0xbb", true},
+		{"bridge:0xbb", false},
+	}
+	for _, tt := range tests {
+		if tripped, err := st.Trip(tt.scope, "test"); err != nil || tripped != tt.tripped {
+			t.Errorf("Trip(%s) = %v, %v, want %v", tt.scope, tripped, err, tt.tripped)
+		}
+	}
+	if _, err := st.Resume(BREAKER_SCOPE_GLOBAL); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewBreakerStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Paused(BREAKER_SCOPE_GLOBAL) != nil {
+		t.Error("resumed scope still paused")
+	}
+	if reopened.Paused("chain:1", "bridge:0xbb") == nil {
+		t.Error("tripped scope not paused after reopening")
+	}
+
+	// the file can not be written, the pause holds in memory
+	reopened.file = filepath.Join(t.TempDir(), "missing", "breaker.gob")
+	if tripped, err := reopened.Trip("chain:1", "test"); err == nil || !tripped {
+		t.Errorf("Trip with a failing write = %v, %v, want tripped with an error", tripped, err)
+	}
+	if reopened.Paused("chain:1") == nil {
+		t.Error("pause lost after a failing write")
+	}
+
+}
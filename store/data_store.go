@@ -2,6 +2,7 @@ package store
 
 import (
 	"fmt"
+	"math/big"
 	"strings"
 	"sync"
 
@@ -14,11 +15,47 @@ import (
 
 var Data *DataStore
 
+// CHECKPOINTS_LIMIT is the number of scanned blocks kept per bridge to find the common ancestor after a reorg
+const CHECKPOINTS_LIMIT = 128
+
 type DataStore struct {
-	mu        sync.RWMutex
-	cacheFile string
-	validate  *validator.Validate
-	blocks    map[dskey]uint64
+	mu          sync.RWMutex
+	cacheFile   string
+	validate    *validator.Validate
+	blocks      map[dskey]uint64
+	checkpoints map[dskey][]Checkpoint
+	audits      map[dskey]*AuditState
+}
+
+// Checkpoint is a scanned block of the bridge cursor
+type Checkpoint struct {
+	Number uint64
+	Hash   string
+}
+
+// AuditState is what the auditor scanned of a bridge, kept so it does not rescan from the start block on every start
+type AuditState struct {
+	Next    uint64                // first block not scanned yet
+	Hash    string                // hash of block Next-1, a reorg changes it
+	Total   *big.Int              // sum of Deposit amounts below Next
+	Settled uint64                // events below are only summed in Total, reorgs are not followed below it
+	Events  map[string]AuditEvent // scanned events from Settled, by lowercase ledger ID
+}
+
+// AuditEvent is a Deposit event scanned by the auditor
+type AuditEvent struct {
+	BlockNumber uint64
+	Amount      *big.Int
+}
+
+func (a *AuditState) copy() *AuditState {
+	c := *a
+	c.Total = new(big.Int).Set(a.Total)
+	c.Events = make(map[string]AuditEvent, len(a.Events))
+	for id, e := range a.Events {
+		c.Events[id] = e
+	}
+	return &c
 }
 
 type dskey struct {
@@ -29,9 +66,11 @@ type dskey struct {
 func NewDataStore(cacheFile string, conf *config.Config) *DataStore {
 
 	ds := &DataStore{
-		cacheFile: cacheFile,
-		validate:  validation.GetInstance(),
-		blocks:    make(map[dskey]uint64),
+		cacheFile:   cacheFile,
+		validate:    validation.GetInstance(),
+		blocks:      make(map[dskey]uint64),
+		checkpoints: make(map[dskey][]Checkpoint),
+		audits:      make(map[dskey]*AuditState),
 	}
 
 	log.WithField("prefix", "store").Debug("reading cache file: ", cacheFile)
@@ -43,12 +82,47 @@ func NewDataStore(cacheFile string, conf *config.Config) *DataStore {
 
 }
 
-func (st *DataStore) AddBlock(lastBlock uint64, chainId int, address string) {
+func (st *DataStore) AddBlock(lastBlock uint64, hash string, chainId int, address string) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	key := dskey{chainId, strings.ToLower(address)}
+	st.blocks[key] = lastBlock
+
+	checkpoints := st.checkpoints[key]
+	if n := len(checkpoints); n > 0 && checkpoints[n-1].Number == lastBlock {
+		checkpoints = checkpoints[:n-1]
+	}
+	checkpoints = append(checkpoints, Checkpoint{lastBlock, hash})
+	if len(checkpoints) > CHECKPOINTS_LIMIT {
+		checkpoints = checkpoints[len(checkpoints)-CHECKPOINTS_LIMIT:]
+	}
+	st.checkpoints[key] = checkpoints
+}
+
+// GetCheckpoints returns scanned blocks of the bridge, oldest first
+func (st *DataStore) GetCheckpoints(chainId int, address string) []Checkpoint {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	return append([]Checkpoint(nil), st.checkpoints[dskey{chainId, strings.ToLower(address)}]...)
+}
+
+// RewindBlock moves the bridge cursor back to blockNumber and drops later checkpoints
+func (st *DataStore) RewindBlock(blockNumber uint64, chainId int, address string) {
 
 	st.mu.Lock()
 	defer st.mu.Unlock()
 
-	st.blocks[dskey{chainId, strings.ToLower(address)}] = lastBlock
+	key := dskey{chainId, strings.ToLower(address)}
+	st.blocks[key] = blockNumber
+
+	checkpoints := st.checkpoints[key]
+	for len(checkpoints) > 0 && checkpoints[len(checkpoints)-1].Number > blockNumber {
+		checkpoints = checkpoints[:len(checkpoints)-1]
+	}
+	st.checkpoints[key] = checkpoints
 }
 
 func (st *DataStore) GetBlock(chainId int, address string) (uint64, error) {
@@ -61,3 +135,23 @@ func (st *DataStore) GetBlock(chainId int, address string) (uint64, error) {
 	}
 	return blockNumber, nil
 }
+
+// GetAudit returns the auditor state of the bridge, false if it was never audited
+func (st *DataStore) GetAudit(chainId int, address string) (*AuditState, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	audit, exists := st.audits[dskey{chainId, strings.ToLower(address)}]
+	if !exists {
+		return nil, false
+	}
+	return audit.copy(), true
+}
+
+// SetAudit records the auditor state of the bridge, written with the cache
+func (st *DataStore) SetAudit(chainId int, address string, audit *AuditState) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.audits[dskey{chainId, strings.ToLower(address)}] = audit.copy()
+}
@@ -10,11 +10,13 @@ import (
 
 // Cache represents the snapshot of the data store.
 type Cache struct {
-	Blocks map[dskey]uint64
-	Time   *time.Time
+	Blocks      map[dskey]uint64
+	Checkpoints map[dskey][]Checkpoint
+	Audits      map[dskey]*AuditState
+	Time        *time.Time
 }
 
-// WriteCache creates a cache (or snapshot) of the data store and stores it in the filesystem.
+// WriteCache creates a cache (or snapshot) of the data store and stores it in the filesystem atomically.
 func (ds *DataStore) WriteCache() error {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
@@ -22,23 +24,13 @@ func (ds *DataStore) WriteCache() error {
 	now := time.Now()
 
 	cacheData := &Cache{
-		Blocks: ds.blocks,
-		Time:   &now,
+		Blocks:      ds.blocks,
+		Checkpoints: ds.checkpoints,
+		Audits:      ds.audits,
+		Time:        &now,
 	}
 
-	file, err := os.Create(ds.cacheFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := gob.NewEncoder(file)
-	err = encoder.Encode(cacheData)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return writeGob(ds.cacheFile, cacheData)
 }
 
 // ReadCache reads a cache (or snapshot) from the filesystem and applies it in-memory.
@@ -64,7 +56,19 @@ func (ds *DataStore) ReadCache(conf *config.Config) error {
 		cache.Blocks = make(map[dskey]uint64)
 	}
 
+	// caches written before checkpoints were introduced have none
+	if cache.Checkpoints == nil {
+		cache.Checkpoints = make(map[dskey][]Checkpoint)
+	}
+
+	// and no auditor state before it was persisted
+	if cache.Audits == nil {
+		cache.Audits = make(map[dskey]*AuditState)
+	}
+
 	ds.blocks = cache.Blocks
+	ds.checkpoints = cache.Checkpoints
+	ds.audits = cache.Audits
 
 	return nil
 }
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewindBlock(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		rewind uint64
+		want   []uint64 // checkpoints left
+	}{
+		{name: "to the last checkpoint", rewind: 300, want: []uint64{100, 200, 300}},
+		{name: "between checkpoints", rewind: 250, want: []uint64{100, 200}},
+		{name: "to a checkpoint", rewind: 200, want: []uint64{100, 200}},
+		{name: "below every checkpoint", rewind: 50, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			ds := NewDataStore(filepath.Join(t.TempDir(), "cache.gob"), nil)
+			for _, n := range []uint64{100, 200, 300} {
+				ds.AddBlock(n, "0xhash", 1, "0xBridge")
+			}
+
+			ds.RewindBlock(tt.rewind, 1, "0xbridge")
+
+			if block, err := ds.GetBlock(1, "0xbridge"); err != nil || block != tt.rewind {
+				t.Errorf("block = %d, %v, want %d", block, err, tt.rewind)
+			}
+			checkpoints := ds.GetCheckpoints(1, "0xBRIDGE")
+			if len(checkpoints) != len(tt.want) {
+				t.Fatalf("%d checkpoints, want %d", len(checkpoints), len(tt.want))
+			}
+			for i, n := range tt.want {
+				if checkpoints[i].Number != n {
+					t.Errorf("checkpoint %d is block %d, want %d", i, checkpoints[i].Number, n)
+				}
+			}
+
+		})
+	}
+
+}
+
+func TestAuditStateCache(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "cache.gob")
+	ds := NewDataStore(file, nil)
+
+	audit := &AuditState{
+		Next:    501,
+		Hash:    "0xabc",
+		Total:   big.NewInt(300),
+		Settled: 100,
+		Events:  map[string]AuditEvent{"1:0xbridge:0xaa:0": {BlockNumber: 400, Amount: big.NewInt(300)}},
+	}
+	ds.SetAudit(1, "0xBridge", audit)
+
+	// the store keeps its own copy
+	audit.Total.SetInt64(0)
+	audit.Events["other"] = AuditEvent{}
+
+	if err := ds.WriteCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, exists := NewDataStore(file, nil).GetAudit(1, "0xbridge")
+	if !exists {
+		t.Fatal("audit state not cached")
+	}
+	if got.Next != 501 || got.Hash != "0xabc" || got.Settled != 100 || got.Total.Int64() != 300 || len(got.Events) != 1 {
+		t.Errorf("audit state = %+v", got)
+	}
+
+	if _, exists := ds.GetAudit(2, "0xbridge"); exists {
+		t.Error("audit state of another chain")
+	}
+
+}
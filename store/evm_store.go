@@ -2,27 +2,45 @@ package store
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/AccumulatedFinance/aevm-bridge/evm"
 	"github.com/AccumulatedFinance/aevm-bridge/validation"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-playground/validator/v10"
 )
 
 var EVM *EVMStore
 
+// bridgeClientKey identifies the client of a bridge on one of its chains
+type bridgeClientKey struct {
+	ChainID int
+	Bridge  string
+}
+
+// signerClientKey identifies the client of a signer on a chain
+type signerClientKey struct {
+	ChainID int
+	Address common.Address
+}
+
 type EVMStore struct {
-	mu       sync.RWMutex
-	validate *validator.Validate
-	clients  map[int]*evm.EVMClient
+	mu            sync.RWMutex
+	validate      *validator.Validate
+	clients       map[int]*evm.EVMClient // default client of each chain
+	bridgeClients map[bridgeClientKey]*evm.EVMClient
+	signerClients map[signerClientKey]*evm.EVMClient
 }
 
 func NewEVMStore() *EVMStore {
 	return &EVMStore{
-		validate: validation.GetInstance(),
-		clients:  make(map[int]*evm.EVMClient),
+		validate:      validation.GetInstance(),
+		clients:       make(map[int]*evm.EVMClient),
+		bridgeClients: make(map[bridgeClientKey]*evm.EVMClient),
+		signerClients: make(map[signerClientKey]*evm.EVMClient),
 	}
 }
 
@@ -35,6 +53,29 @@ func (st *EVMStore) AddClient(client *evm.EVMClient) {
 	st.mu.Lock()
 	defer st.mu.Unlock()
 	st.clients[client.ChainID] = client
+	st.addSigner(client)
+}
+
+// AddBridgeClient sets the client signing txs of the bridge on the client chain
+func (st *EVMStore) AddBridgeClient(bridge string, client *evm.EVMClient) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.bridgeClients[bridgeClientKey{client.ChainID, strings.ToLower(bridge)}] = client
+	st.addSigner(client)
+}
+
+// addSigner indexes the client by its signer, the caller must hold the lock
+func (st *EVMStore) addSigner(client *evm.EVMClient) {
+
+	if client.Signer == nil {
+		return
+	}
+
+	key := signerClientKey{client.ChainID, client.PublicKey}
+	if _, exists := st.signerClients[key]; !exists {
+		st.signerClients[key] = client
+	}
 }
 
 func (st *EVMStore) GetClientByChainId(chainID int) (*evm.EVMClient, error) {
@@ -49,6 +90,32 @@ func (st *EVMStore) GetClientByChainId(chainID int) (*evm.EVMClient, error) {
 	return client, nil
 }
 
+// GetBridgeClient returns the client of the bridge on the chain, the default client of the chain if the bridge has no own signer
+func (st *EVMStore) GetBridgeClient(chainID int, bridge string) (*evm.EVMClient, error) {
+	st.mu.RLock()
+	client, exists := st.bridgeClients[bridgeClientKey{chainID, strings.ToLower(bridge)}]
+	st.mu.RUnlock()
+
+	if exists {
+		return client, nil
+	}
+
+	return st.GetClientByChainId(chainID)
+}
+
+// GetClientBySigner returns a client signing with the address on the chain
+func (st *EVMStore) GetClientBySigner(chainID int, address common.Address) (*evm.EVMClient, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	client, exists := st.signerClients[signerClientKey{chainID, address}]
+	if !exists {
+		return nil, fmt.Errorf("no client with signer %s on chainID %d", address.Hex(), chainID)
+	}
+
+	return client, nil
+}
+
 func (st *EVMStore) GeAllClients() map[int]*evm.EVMClient {
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// writeGob persists v to file atomically: it is encoded to a temporary file, synced, renamed over file and the rename is synced
+func writeGob(file string, v interface{}) error {
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(file))
+}
+
+// readGob decodes file into v, exists is false if there is no file
+func readGob(file string, v interface{}) (exists bool, err error) {
+
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	return true, gob.NewDecoder(f).Decode(v)
+}
+
+// syncDir makes renames and new files in dir survive a crash
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,415 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var Ledger *LedgerStore
+
+// DepositStatus is the lifecycle stage of a deposit in the ledger
+type DepositStatus string
+
+const (
+	DepositPending          DepositStatus = "pending"           // observed on the source chain, mint not signed yet
+	DepositSubmitted        DepositStatus = "submitted"         // mint signed, recorded and handed to the node
+	DepositMined            DepositStatus = "mined"             // mint included with a successful receipt
+	DepositFailed           DepositStatus = "failed"            // mint reverted, needs operator review
+	DepositOrphaned         DepositStatus = "orphaned"          // source block was reorganized out before the mint
+	DepositSkipped          DepositStatus = "skipped"           // nothing to mint, the amount is below the destination token precision
+	DepositAwaitingApproval DepositStatus = "awaiting_approval" // held for operator approval before the mint
+	DepositRejected         DepositStatus = "rejected"          // rejected by an operator, never minted
+	DepositAttested         DepositStatus = "attested"          // attestation signed and published, another relayer submits the mint
+	DepositProposed         DepositStatus = "proposed"          // mint proposed as a Safe tx, executed by the Safe owners
+)
+
+// ErrDepositChanged is returned by UpdateDeposit if the stored deposit changed since it was read
+var ErrDepositChanged = errors.New("deposit changed")
+
+// DepositKindFee marks the mint of the bridge fee of a deposit to the treasury, it shares the source event of the deposit
+const DepositKindFee = "fee"
+
+// Deposit is a single bridge event and the state of the tx executing it:
+// a Deposit on the source chain minted on the destination chain, or a withdrawal released on the source chain
+type Deposit struct {
+	ChainID           int
+	Address           string
+	Direction         string
+	Kind              string // DepositKindFee for treasury fee mints, empty for bridged amounts
+	Bridge            string // address of the bridge that recorded the deposit, shared by both directions
+	TargetChainID     int    // chain where the deposit is executed
+	TxHash            string
+	LogIndex          uint
+	BlockNumber       uint64
+	BlockHash         string
+	Receiver          string
+	Amount            *big.Int // in source token units
+	MintAmount        *big.Int // Amount scaled to target token units
+	Dust              *big.Int // remainder of Amount lost to scaling, in source token units
+	Fee               *big.Int // bridge fee charged on Amount, in source token units
+	Status            DepositStatus
+	Attestation       []byte // own EIP-712 attestation signature of the execution
+	AttestationDigest string // EIP-712 hash signed by Attestation
+	MintChainID       int
+	MintFrom          string // signer of the mint tx, the Safe of proposed mints
+	SafeTxHash        string // EIP-712 hash of the Safe tx proposing the mint, MintNonce is its Safe nonce
+	SafeUnpublished   bool   // publishing the Safe tx failed, it may still have reached the service
+	MintTxHash        string
+	MintNonce         uint64
+	MintRawTx         []byte   // signed mint tx, kept for rebroadcasts
+	ReplacedTxs       []string // hashes of earlier mint txs with the same nonce, any of them may still be mined
+	MintBlock         uint64
+	SubmittedAt       time.Time // first submission, places the mint in the cap windows
+	ReplacedAt        time.Time // last replacement of the mint tx, zero if never replaced
+	Error             string
+	Approved          bool   // released from the approval queue by an operator
+	ApprovedReason    string // hold reason the operator overrode, other reasons hold the deposit again
+	ReviewedBy        string // operator who approved or rejected the deposit
+	ReviewedAt        time.Time
+	Orphaned          bool // source block was reorganized out, needs operator review unless observed again
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type ledgerKey struct {
+	ChainID  int
+	Address  string
+	TxHash   string
+	LogIndex uint
+	Kind     string
+}
+
+// LedgerStore is the persistent per-deposit ledger, every change is flushed to disk before it is visible.
+// Changes are appended to a log next to the snapshot file, which is rewritten every LEDGER_COMPACT_RECORDS changes.
+type LedgerStore struct {
+	mu         sync.RWMutex
+	file       string
+	deposits   map[ledgerKey]*Deposit
+	log        *os.File // append log, nil until the first change after opening or compacting
+	logSize    int64    // end of the last complete record in the log
+	generation uint64   // snapshot generation, log records of other generations are stale
+	records    int      // log records since the last compaction
+}
+
+// NewLedgerStore opens the ledger file, a missing file starts an empty ledger
+func NewLedgerStore(file string) (*LedgerStore, error) {
+
+	st := &LedgerStore{
+		file:     file,
+		deposits: make(map[ledgerKey]*Deposit),
+	}
+
+	log.WithField("prefix", "store").Debug("reading ledger file: ", file)
+	if err := st.read(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger %s: %w", file, err)
+	}
+
+	return st, nil
+
+}
+
+// ID returns a human readable identifier of the deposit
+func (d *Deposit) ID() string {
+	if d.Kind != "" {
+		return fmt.Sprintf("%d:%s:%s:%d:%s", d.ChainID, d.Address, d.TxHash, d.LogIndex, d.Kind)
+	}
+	return fmt.Sprintf("%d:%s:%s:%d", d.ChainID, d.Address, d.TxHash, d.LogIndex)
+}
+
+func (d *Deposit) key() ledgerKey {
+	return ledgerKey{d.ChainID, strings.ToLower(d.Address), strings.ToLower(d.TxHash), d.LogIndex, d.Kind}
+}
+
+func (d *Deposit) copy() *Deposit {
+	c := *d
+	if d.Amount != nil {
+		c.Amount = new(big.Int).Set(d.Amount)
+	}
+	if d.MintAmount != nil {
+		c.MintAmount = new(big.Int).Set(d.MintAmount)
+	}
+	if d.Dust != nil {
+		c.Dust = new(big.Int).Set(d.Dust)
+	}
+	if d.Fee != nil {
+		c.Fee = new(big.Int).Set(d.Fee)
+	}
+	c.Attestation = append([]byte(nil), d.Attestation...)
+	c.MintRawTx = append([]byte(nil), d.MintRawTx...)
+	c.ReplacedTxs = append([]string(nil), d.ReplacedTxs...)
+	return &c
+}
+
+// AddDeposit records a newly observed deposit as pending, or awaiting approval if it may have been executed under another log index.
+// If the deposit is already known, the existing record is returned and created is false.
+func (st *LedgerStore) AddDeposit(d *Deposit) (deposit *Deposit, created bool, err error) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	key := d.key()
+	if existing, exists := st.deposits[key]; exists {
+		if existing.Orphaned {
+			return st.reconfirm(existing, d)
+		}
+		return existing.copy(), false, nil
+	}
+
+	now := time.Now()
+	deposit = d.copy()
+	deposit.Status = DepositPending
+	// a reorg can move the deposit to another log index, its executed record must not be paid again,
+	// the fee mint waits for the held deposit
+	if executed := st.findReexecution(d); executed != nil && d.Kind == "" {
+		deposit.Status = DepositAwaitingApproval
+		deposit.Error = "deposit tx reorged, possibly executed as " + executed.ID()
+		log.WithField("prefix", "store").Error("ALERT: ", deposit.ID(), " matches orphaned ", executed.Status, " ", executed.ID(), ", awaiting approval")
+	}
+	deposit.CreatedAt = now
+	deposit.UpdatedAt = now
+
+	st.deposits[key] = deposit
+	if err := st.write(deposit); err != nil {
+		delete(st.deposits, key)
+		return nil, false, err
+	}
+
+	return deposit.copy(), true, nil
+}
+
+// findReexecution returns an orphaned executed record of the same deposit tx, receiver and amount under another log index, the caller must hold the lock
+func (st *LedgerStore) findReexecution(d *Deposit) *Deposit {
+
+	for k, existing := range st.deposits {
+		if !existing.Orphaned || existing.Status == DepositOrphaned || existing.Status == DepositRejected {
+			continue
+		}
+		if k.ChainID != d.ChainID || k.Address != strings.ToLower(d.Address) || k.TxHash != strings.ToLower(d.TxHash) || k.Kind != d.Kind || k.LogIndex == d.LogIndex {
+			continue
+		}
+		if strings.EqualFold(existing.Receiver, d.Receiver) && existing.Amount.Cmp(d.Amount) == 0 {
+			return existing
+		}
+	}
+
+	return nil
+}
+
+// reconfirm clears the orphaned flag of a deposit observed again on the canonical chain, the caller must hold the lock
+func (st *LedgerStore) reconfirm(existing *Deposit, d *Deposit) (*Deposit, bool, error) {
+
+	if !strings.EqualFold(existing.Receiver, d.Receiver) || existing.Amount.Cmp(d.Amount) != 0 {
+		log.WithField("prefix", "store").Error("orphaned deposit ", existing.ID(), " reappeared with different data, keeping it flagged")
+		return existing.copy(), false, nil
+	}
+
+	updated := existing.copy()
+	updated.BlockNumber = d.BlockNumber
+	updated.BlockHash = d.BlockHash
+	updated.Orphaned = false
+	if updated.Status == DepositOrphaned {
+		updated.Status = DepositPending
+	}
+	updated.UpdatedAt = time.Now()
+
+	key := existing.key()
+	st.deposits[key] = updated
+	if err := st.write(updated); err != nil {
+		st.deposits[key] = existing
+		return nil, false, err
+	}
+
+	log.WithField("prefix", "store").Info("orphaned deposit ", updated.ID(), " reconfirmed in block ", updated.BlockNumber)
+
+	return updated.copy(), false, nil
+}
+
+// FlagOrphaned flags deposits of the bridge executed on targetChainId from blocks above ancestor as orphaned.
+// Deposits that were not minted yet are withheld, minted ones are returned for operator review.
+func (st *LedgerStore) FlagOrphaned(chainId int, address string, targetChainId int, ancestor uint64) ([]*Deposit, error) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	previous := make(map[ledgerKey]*Deposit)
+	var changed, minted []*Deposit
+
+	for k, d := range st.deposits {
+		if k.ChainID != chainId || k.Address != strings.ToLower(address) || d.BlockNumber <= ancestor || d.Orphaned {
+			continue
+		}
+		if d.TargetChainID != 0 && d.TargetChainID != targetChainId {
+			continue
+		}
+		previous[k] = d
+		updated := d.copy()
+		updated.Orphaned = true
+		updated.UpdatedAt = time.Now()
+		switch updated.Status {
+		case DepositPending, DepositSkipped, DepositAwaitingApproval:
+			updated.Status = DepositOrphaned
+		case DepositRejected:
+			// never minted, the flag is enough
+		default:
+			// submitted, mined, failed or attested, the published attestation may still be used by others
+			minted = append(minted, updated.copy())
+		}
+		st.deposits[k] = updated
+		changed = append(changed, updated)
+	}
+
+	if len(previous) == 0 {
+		return nil, nil
+	}
+
+	if err := st.write(changed...); err != nil {
+		for k, d := range previous {
+			st.deposits[k] = d
+		}
+		return nil, err
+	}
+
+	sortDeposits(minted)
+
+	return minted, nil
+}
+
+// UpdateDeposit applies update to the stored deposit and persists the ledger.
+// The update is compare-and-set: it fails with ErrDepositChanged unless the stored deposit is still in the expected status
+// and was not flagged orphaned after d was read. If persisting fails, the in-memory record is left untouched.
+// Signed txs, Safe proposals and attestations are recorded with it before they leave the relayer, so a crash never loses one.
+func (st *LedgerStore) UpdateDeposit(d *Deposit, expected DepositStatus, update func(d *Deposit)) (*Deposit, error) {
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	key := d.key()
+	existing, exists := st.deposits[key]
+	if !exists {
+		return nil, fmt.Errorf("deposit %s not found", d.ID())
+	}
+	if existing.Status != expected {
+		return nil, fmt.Errorf("%w: %s is %s, expected %s", ErrDepositChanged, d.ID(), existing.Status, expected)
+	}
+	if existing.Orphaned && !d.Orphaned {
+		return nil, fmt.Errorf("%w: %s was orphaned", ErrDepositChanged, d.ID())
+	}
+
+	updated := existing.copy()
+	update(updated)
+	updated.UpdatedAt = time.Now()
+
+	st.deposits[key] = updated
+	if err := st.write(updated); err != nil {
+		st.deposits[key] = existing
+		return nil, err
+	}
+
+	return updated.copy(), nil
+}
+
+// GetDeposit returns the deposit by its source chain coordinates
+func (st *LedgerStore) GetDeposit(chainId int, address string, txHash string, logIndex uint) (*Deposit, error) {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	d, exists := st.deposits[ledgerKey{chainId, strings.ToLower(address), strings.ToLower(txHash), logIndex, ""}]
+	if !exists {
+		return nil, fmt.Errorf("deposit with txHash=%s and logIndex=%d not found", txHash, logIndex)
+	}
+
+	return d.copy(), nil
+}
+
+// GetDepositsByStatus returns deposits of all bridges in the given status, ordered by block number
+func (st *LedgerStore) GetDepositsByStatus(status DepositStatus) []*Deposit {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var deposits []*Deposit
+	for _, d := range st.deposits {
+		if d.Status == status {
+			deposits = append(deposits, d.copy())
+		}
+	}
+
+	sortDeposits(deposits)
+
+	return deposits
+}
+
+// GetDeposits returns deposits of the bridge in the given status, ordered by block number
+func (st *LedgerStore) GetDeposits(chainId int, address string, status DepositStatus) []*Deposit {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var deposits []*Deposit
+	for k, d := range st.deposits {
+		if k.ChainID == chainId && k.Address == strings.ToLower(address) && d.Status == status {
+			deposits = append(deposits, d.copy())
+		}
+	}
+
+	sortDeposits(deposits)
+
+	return deposits
+}
+
+// GetExecutedSince returns deposits in the given direction executed or vouched for after since, ordered by block number:
+// submitted, proposed, attested or mined, and pending ones carrying an own attestation
+func (st *LedgerStore) GetExecutedSince(direction string, since time.Time) []*Deposit {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var deposits []*Deposit
+	for _, d := range st.deposits {
+		if d.Direction != direction {
+			continue
+		}
+		switch d.Status {
+		case DepositSubmitted, DepositMined, DepositProposed, DepositAttested:
+		case DepositPending:
+			if d.AttestationDigest == "" {
+				continue
+			}
+		default:
+			continue
+		}
+		if d.SubmittedAt.After(since) {
+			deposits = append(deposits, d.copy())
+		}
+	}
+
+	sortDeposits(deposits)
+
+	return deposits
+}
+
+// GetBridgeDeposits returns all deposits of the bridge regardless of status, ordered by block number
+func (st *LedgerStore) GetBridgeDeposits(chainId int, address string) []*Deposit {
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var deposits []*Deposit
+	for k, d := range st.deposits {
+		if k.ChainID == chainId && k.Address == strings.ToLower(address) {
+			deposits = append(deposits, d.copy())
+		}
+	}
+
+	sortDeposits(deposits)
+
+	return deposits
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LEDGER_LOG_SUFFIX names the append log next to the ledger snapshot
+const LEDGER_LOG_SUFFIX = ".log"
+
+// LEDGER_COMPACT_RECORDS is the number of log records after which the ledger is rewritten as a snapshot
+const LEDGER_COMPACT_RECORDS = 1000
+
+// LEDGER_PRUNE_AFTER is the age of settled deposits whose signed txs and attestations are dropped on compaction
+const LEDGER_PRUNE_AFTER = 30 * 24 * time.Hour
+
+// ledgerSnapshot is the ledger as of the last compaction, log records of the same generation are applied on top of it
+type ledgerSnapshot struct {
+	Generation uint64
+	Deposits   []*Deposit
+}
+
+// ledgerRecord is a change in the append log, deposits are stored as they are after the change
+type ledgerRecord struct {
+	Generation uint64
+	Deposits   []*Deposit
+}
+
+// write appends the changed deposits to the log, or compacts the ledger once the log is long enough, the caller must hold the lock
+func (st *LedgerStore) write(changed ...*Deposit) error {
+
+	if st.records >= LEDGER_COMPACT_RECORDS {
+		return st.compact()
+	}
+
+	if st.log == nil {
+		file, err := os.OpenFile(st.file+LEDGER_LOG_SUFFIX, os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		// a torn record of a crash or a failed append is cut off before appending
+		if err := file.Truncate(st.logSize); err != nil {
+			file.Close()
+			return err
+		}
+		if err := syncDir(filepath.Dir(st.file)); err != nil {
+			file.Close()
+			return err
+		}
+		st.log = file
+	}
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(&ledgerRecord{Generation: st.generation, Deposits: changed}); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 8, 8+payload.Len())
+	binary.BigEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	frame = append(frame, payload.Bytes()...)
+
+	if _, err := st.log.WriteAt(frame, st.logSize); err != nil {
+		st.closeLog()
+		return err
+	}
+
+	// make sure the change hits the disk before the mint is sent
+	if err := st.log.Sync(); err != nil {
+		st.closeLog()
+		return err
+	}
+
+	st.logSize += int64(len(frame))
+	st.records++
+
+	return nil
+}
+
+// compact writes the ledger as a new snapshot generation and empties the log, the caller must hold the lock.
+// Settled deposits are pruned: their record stays so they are never executed again, the signed tx and attestation go.
+func (st *LedgerStore) compact() error {
+
+	cutoff := time.Now().Add(-LEDGER_PRUNE_AFTER)
+	pruned := make(map[ledgerKey]*Deposit)
+
+	deposits := make([]*Deposit, 0, len(st.deposits))
+	for k, d := range st.deposits {
+		if d.prunable(cutoff) {
+			d = d.copy()
+			d.Attestation = nil
+			d.MintRawTx = nil
+			d.ReplacedTxs = nil
+			pruned[k] = d
+		}
+		deposits = append(deposits, d)
+	}
+
+	if err := writeGob(st.file, &ledgerSnapshot{Generation: st.generation + 1, Deposits: deposits}); err != nil {
+		return err
+	}
+
+	for k, d := range pruned {
+		st.deposits[k] = d
+	}
+
+	// records of earlier generations are skipped on read, the next append truncates them
+	st.generation++
+	st.closeLog()
+	st.logSize = 0
+	st.records = 0
+
+	return nil
+}
+
+// prunable returns true if the deposit was settled before cutoff and still carries its signed tx or attestation
+func (d *Deposit) prunable(cutoff time.Time) bool {
+
+	switch d.Status {
+	case DepositMined, DepositRejected, DepositSkipped:
+	default:
+		return false
+	}
+
+	return d.UpdatedAt.Before(cutoff) && (len(d.Attestation) > 0 || len(d.MintRawTx) > 0 || len(d.ReplacedTxs) > 0)
+}
+
+// closeLog closes the append log, it is reopened and truncated to the last complete record by the next write
+func (st *LedgerStore) closeLog() {
+
+	if st.log != nil {
+		st.log.Close()
+		st.log = nil
+	}
+}
+
+// read loads the ledger snapshot and replays the log from the filesystem, the caller must hold the lock
+func (st *LedgerStore) read() error {
+
+	var snapshot ledgerSnapshot
+	if _, err := readGob(st.file, &snapshot); err != nil {
+		return err
+	}
+
+	for _, d := range snapshot.Deposits {
+		st.deposits[d.key()] = d
+	}
+	st.generation = snapshot.Generation
+
+	data, err := os.ReadFile(st.file + LEDGER_LOG_SUFFIX)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	for int64(len(data))-offset >= 8 {
+
+		size := int64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		checksum := binary.BigEndian.Uint32(data[offset+4 : offset+8])
+		end := offset + 8 + size
+		if end > int64(len(data)) || crc32.ChecksumIEEE(data[offset+8:end]) != checksum {
+			break
+		}
+
+		var record ledgerRecord
+		if err := gob.NewDecoder(bytes.NewReader(data[offset+8 : end])).Decode(&record); err != nil {
+			break
+		}
+		offset = end
+
+		// written before the last compaction, the snapshot already has them
+		if record.Generation != st.generation {
+			continue
+		}
+		for _, d := range record.Deposits {
+			st.deposits[d.key()] = d
+		}
+		st.records++
+
+	}
+
+	if offset < int64(len(data)) {
+		log.WithField("prefix", "store").Warn("Dropping ", int64(len(data))-offset, " bytes of a torn record at the end of ", st.file+LEDGER_LOG_SUFFIX)
+	}
+	st.logSize = offset
+
+	return nil
+}
+
+// sortDeposits orders deposits as they appeared on the source chain
+func sortDeposits(deposits []*Deposit) {
+	sort.Slice(deposits, func(i, j int) bool {
+		if deposits[i].BlockNumber != deposits[j].BlockNumber {
+			return deposits[i].BlockNumber < deposits[j].BlockNumber
+		}
+		if deposits[i].TxHash != deposits[j].TxHash {
+			return deposits[i].TxHash < deposits[j].TxHash
+		}
+		if deposits[i].LogIndex != deposits[j].LogIndex {
+			return deposits[i].LogIndex < deposits[j].LogIndex
+		}
+		return deposits[i].Kind < deposits[j].Kind
+	})
+}
@@ -0,0 +1,345 @@
+package store
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newDeposit returns a deposit of block 100 with a unique log index
+func newDeposit(logIndex uint) *Deposit {
+	return &Deposit{
+		ChainID:       1,
+		Address:       "0x00000000000000000000000000000000000000Bb",
+		Direction:     "deposit",
+		TargetChainID: 619001,
+		TxHash:        "0x00000000000000000000000000000000000000000000000000000000000000aa",
+		LogIndex:      logIndex,
+		BlockNumber:   100,
+		Receiver:      "0x00000000000000000000000000000000000000Cc",
+		Amount:        big.NewInt(1000),
+	}
+}
+
+// addDeposit records d in the ledger and moves it to status
+func addDeposit(t *testing.T, st *LedgerStore, d *Deposit, status DepositStatus) *Deposit {
+	t.Helper()
+	added, created, err := st.AddDeposit(d)
+	if err != nil || !created {
+		t.Fatalf("AddDeposit: created=%v err=%v", created, err)
+	}
+	if status == DepositPending {
+		return added
+	}
+	updated, err := st.UpdateDeposit(added, DepositPending, func(d *Deposit) { d.Status = status })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return updated
+}
+
+func newTestLedger(t *testing.T) (*LedgerStore, string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "ledger.gob")
+	st, err := NewLedgerStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st, file
+}
+func TestUpdateDeposit(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		expected DepositStatus
+		orphan   bool // the reorg watcher flags the deposit after it was read
+		wantErr  error
+	}{
+		{name: "expected status", expected: DepositPending},
+		{name: "status changed", expected: DepositSubmitted, wantErr: ErrDepositChanged},
+		{name: "orphaned since read", expected: DepositPending, orphan: true, wantErr: ErrDepositChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			st, _ := newTestLedger(t)
+			d := addDeposit(t, st, newDeposit(1), DepositPending)
+			if tt.orphan {
+				if _, err := st.FlagOrphaned(1, d.Address, 619001, 99); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := st.UpdateDeposit(d, tt.expected, func(d *Deposit) { d.Status = DepositSubmitted })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, _ := st.GetDeposit(1, d.Address, d.TxHash, d.LogIndex)
+			want := DepositSubmitted
+			if tt.wantErr != nil {
+				want = DepositPending
+				if tt.orphan {
+					want = DepositOrphaned
+				}
+			}
+			if stored.Status != want {
+				t.Errorf("status = %s, want %s", stored.Status, want)
+			}
+
+		})
+	}
+
+}
+func TestFlagOrphaned(t *testing.T) {
+
+	tests := []struct {
+		status     DepositStatus
+		wantStatus DepositStatus
+		minted     bool
+	}{
+		{DepositPending, DepositOrphaned, false},
+		{DepositSkipped, DepositOrphaned, false},
+		{DepositAwaitingApproval, DepositOrphaned, false},
+		{DepositRejected, DepositRejected, false},
+		{DepositSubmitted, DepositSubmitted, true},
+		{DepositMined, DepositMined, true},
+		{DepositFailed, DepositFailed, true},
+		{DepositAttested, DepositAttested, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+
+			st, _ := newTestLedger(t)
+			d := addDeposit(t, st, newDeposit(1), tt.status)
+
+			// the ancestor is at or above the block, nothing is orphaned
+			if minted, err := st.FlagOrphaned(1, d.Address, 619001, 100); err != nil || len(minted) != 0 {
+				t.Fatalf("ancestor 100: minted=%d err=%v", len(minted), err)
+			}
+
+			minted, err := st.FlagOrphaned(1, d.Address, 619001, 99)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(minted) == 1) != tt.minted {
+				t.Errorf("%d minted returned, want minted=%v", len(minted), tt.minted)
+			}
+
+			stored, _ := st.GetDeposit(1, d.Address, d.TxHash, d.LogIndex)
+			if stored.Status != tt.wantStatus || !stored.Orphaned {
+				t.Errorf("status = %s orphaned=%v, want %s orphaned", stored.Status, stored.Orphaned, tt.wantStatus)
+			}
+
+			// observed again on the canonical chain
+			_, created, err := st.AddDeposit(newDeposit(1))
+			if err != nil || created {
+				t.Fatalf("AddDeposit again: created=%v err=%v", created, err)
+			}
+			stored, _ = st.GetDeposit(1, d.Address, d.TxHash, d.LogIndex)
+			wantStatus := tt.wantStatus
+			if wantStatus == DepositOrphaned {
+				wantStatus = DepositPending
+			}
+			if stored.Status != wantStatus || stored.Orphaned {
+				t.Errorf("reconfirmed status = %s orphaned=%v, want %s", stored.Status, stored.Orphaned, wantStatus)
+			}
+
+		})
+	}
+
+}
+func TestAddDepositReexecution(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		status DepositStatus // of the orphaned record at log index 1
+		amount int64         // of the deposit reappearing at log index 2
+		want   DepositStatus
+	}{
+		{name: "mined under another log index", status: DepositMined, amount: 1000, want: DepositAwaitingApproval},
+		{name: "submitted under another log index", status: DepositSubmitted, amount: 1000, want: DepositAwaitingApproval},
+		{name: "never executed", status: DepositPending, amount: 1000, want: DepositPending},
+		{name: "different amount", status: DepositMined, amount: 999, want: DepositPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			st, _ := newTestLedger(t)
+			addDeposit(t, st, newDeposit(1), tt.status)
+			if _, err := st.FlagOrphaned(1, newDeposit(1).Address, 619001, 99); err != nil {
+				t.Fatal(err)
+			}
+
+			moved := newDeposit(2)
+			moved.Amount = big.NewInt(tt.amount)
+			added, created, err := st.AddDeposit(moved)
+			if err != nil || !created {
+				t.Fatalf("AddDeposit: created=%v err=%v", created, err)
+			}
+			if added.Status != tt.want {
+				t.Errorf("status = %s, want %s", added.Status, tt.want)
+			}
+
+		})
+	}
+
+}
+func TestLedgerPersistence(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, file string) // damages the files before reopening
+		want    map[uint]DepositStatus
+	}{
+		{
+			name: "log replayed",
+			want: map[uint]DepositStatus{1: DepositMined, 2: DepositSubmitted, 3: DepositPending},
+		},
+		{
+			name: "torn record dropped",
+			prepare: func(t *testing.T, file string) {
+				f, err := os.OpenFile(file+LEDGER_LOG_SUFFIX, os.O_WRONLY|os.O_APPEND, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.Write([]byte{0, 0, 1, 0, 1, 2, 3}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[uint]DepositStatus{1: DepositMined, 2: DepositSubmitted, 3: DepositPending},
+		},
+		{
+			name: "corrupted last record dropped",
+			prepare: func(t *testing.T, file string) {
+				data, err := os.ReadFile(file + LEDGER_LOG_SUFFIX)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data[len(data)-1] ^= 0xff
+				if err := os.WriteFile(file+LEDGER_LOG_SUFFIX, data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[uint]DepositStatus{1: DepositMined, 2: DepositSubmitted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			st, file := newTestLedger(t)
+			addDeposit(t, st, newDeposit(1), DepositMined)
+			addDeposit(t, st, newDeposit(2), DepositSubmitted)
+			addDeposit(t, st, newDeposit(3), DepositPending)
+			st.closeLog()
+
+			if tt.prepare != nil {
+				tt.prepare(t, file)
+			}
+
+			reopened, err := NewLedgerStore(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(reopened.GetBridgeDeposits(1, newDeposit(1).Address)); got != len(tt.want) {
+				t.Fatalf("%d deposits, want %d", got, len(tt.want))
+			}
+			for logIndex, status := range tt.want {
+				d, err := reopened.GetDeposit(1, newDeposit(1).Address, newDeposit(1).TxHash, logIndex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d.Status != status {
+					t.Errorf("deposit %d is %s, want %s", logIndex, d.Status, status)
+				}
+			}
+
+			// appends after a damaged tail are replayed too
+			addDeposit(t, reopened, newDeposit(9), DepositPending)
+			reopened.closeLog()
+			again, err := NewLedgerStore(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := again.GetDeposit(1, newDeposit(9).Address, newDeposit(9).TxHash, 9); err != nil {
+				t.Error(err)
+			}
+
+		})
+	}
+
+}
+func TestLedgerCompaction(t *testing.T) {
+
+	st, file := newTestLedger(t)
+
+	old := newDeposit(1)
+	old.MintRawTx = []byte{1, 2, 3}
+	mined := addDeposit(t, st, old, DepositMined)
+	addDeposit(t, st, newDeposit(2), DepositSubmitted)
+
+	// settle the mined deposit long ago
+	st.mu.Lock()
+	st.deposits[mined.key()].UpdatedAt = time.Now().Add(-LEDGER_PRUNE_AFTER - time.Hour)
+	st.deposits[mined.key()].MintRawTx = []byte{1, 2, 3}
+	st.mu.Unlock()
+
+	st.records = LEDGER_COMPACT_RECORDS
+	addDeposit(t, st, newDeposit(3), DepositPending)
+	if st.generation != 1 {
+		t.Fatalf("generation = %d, want 1", st.generation)
+	}
+	stale, err := os.ReadFile(file + LEDGER_LOG_SUFFIX)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash right after the snapshot leaves records of the previous generation in the log
+	st.closeLog()
+	if err := os.WriteFile(file+LEDGER_LOG_SUFFIX, stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewLedgerStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.records != 0 {
+		t.Errorf("%d records replayed, want the stale ones skipped", reopened.records)
+	}
+
+	tests := []struct {
+		logIndex uint
+		status   DepositStatus
+		rawTx    bool
+	}{
+		{1, DepositMined, false},
+		{2, DepositSubmitted, false},
+		{3, DepositPending, false},
+	}
+	for _, tt := range tests {
+		d, err := reopened.GetDeposit(1, old.Address, old.TxHash, tt.logIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Status != tt.status {
+			t.Errorf("deposit %d is %s, want %s", tt.logIndex, d.Status, tt.status)
+		}
+		if (len(d.MintRawTx) > 0) != tt.rawTx {
+			t.Errorf("deposit %d has raw tx %x", tt.logIndex, d.MintRawTx)
+		}
+	}
+
+	// the pruned deposit is still known, it is never recorded again
+	if _, created, err := reopened.AddDeposit(newDeposit(1)); err != nil || created {
+		t.Errorf("pruned deposit recorded again: created=%v err=%v", created, err)
+	}
+
+}
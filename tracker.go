@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const TRACKER_INTERVAL = 15 * time.Second
+
+// TRACKER_DROPPED_POLLS is the number of polls without any receipt before a mint whose nonce was taken is held
+const TRACKER_DROPPED_POLLS = 4
+
+// droppedPolls counts polls of mints whose nonce was taken by another tx, by deposit ID
+var droppedPolls = make(map[string]int)
+
+// trackMints polls receipts of submitted mints and moves deposits to mined or failed, mints whose nonce was taken are held for review
+func trackMints(die chan bool) {
+
+	for {
+
+		select {
+		default:
+
+			time.Sleep(TRACKER_INTERVAL)
+
+			submitted := store.Ledger.GetDepositsByStatus(store.DepositSubmitted)
+			for _, deposit := range submitted {
+				if err := trackMint(deposit); err != nil {
+					log.WithField("prefix", "tracker").Error("failed to track mint of deposit ", deposit.ID(), ": ", err)
+				}
+			}
+
+			checkStuckNonces(submitted)
+
+		case <-die:
+			return
+		}
+
+	}
+
+}
+
+// trackMint checks the outcome of a single submitted mint
+func trackMint(deposit *store.Deposit) error {
+
+	client, err := mintClient(deposit)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := findReceipt(client, deposit)
+	if err != nil {
+		return err
+	}
+
+	var tx *types.Transaction
+	if len(deposit.MintRawTx) > 0 {
+		if tx, err = evm.DecodeTx(deposit.MintRawTx); err != nil {
+			return err
+		}
+	}
+
+	// mined
+	if receipt != nil {
+
+		// an earlier tx was mined before its replacement, the revert replay needs the mined one
+		if receipt.TxHash != common.HexToHash(deposit.MintTxHash) {
+			tx = nil
+		}
+
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			log.WithField("prefix", "tracker").Info("Mint of deposit ", deposit.ID(), " mined in block ", receipt.BlockNumber, ": ", deposit.MintTxHash)
+			recordSuccess(depositScope(deposit))
+			_, err = store.Ledger.UpdateDeposit(deposit, store.DepositSubmitted, func(d *store.Deposit) {
+				d.Status = store.DepositMined
+				d.MintTxHash = receipt.TxHash.Hex()
+				d.MintBlock = receipt.BlockNumber.Uint64()
+				d.Error = ""
+			})
+			return err
+		}
+
+		reason := "reverted"
+		if tx != nil {
+			if reason, err = client.GetRevertReason(tx, receipt.BlockNumber); err != nil {
+				log.WithField("prefix", "tracker").Error("failed to get revert reason of ", deposit.MintTxHash, ": ", err)
+				reason = "reverted"
+			}
+		}
+
+		log.WithField("prefix", "tracker").Error("ALERT: mint of deposit ", deposit.ID(), " reverted in tx ", deposit.MintTxHash, ": ", reason)
+		recordRevert(depositScope(deposit))
+		_, err = store.Ledger.UpdateDeposit(deposit, store.DepositSubmitted, func(d *store.Deposit) {
+			d.Status = store.DepositFailed
+			d.MintTxHash = receipt.TxHash.Hex()
+			d.MintBlock = receipt.BlockNumber.Uint64()
+			d.Error = reason
+		})
+		return err
+
+	}
+
+	// without the signed tx there is nothing to compare or rebroadcast
+	if tx == nil {
+		log.WithField("prefix", "tracker").Warn("Mint of deposit ", deposit.ID(), " not mined yet: ", deposit.MintTxHash)
+		return nil
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := client.GetNonce(from)
+	if err != nil {
+		return err
+	}
+
+	// the nonce was consumed by another tx, either one of ours the endpoint lost or a foreign one
+	if nonce > tx.Nonce() {
+		return checkDropped(client, deposit, tx.Nonce())
+	}
+
+	// the current tx was sent at the last replacement
+	sentAt := deposit.SubmittedAt
+	if deposit.ReplacedAt.After(sentAt) {
+		sentAt = deposit.ReplacedAt
+	}
+
+	// waiting for too long, replace with bumped gas
+	if client.ReplaceAfter > 0 && time.Since(sentAt) > client.ReplaceAfter {
+		replacement, err := client.BumpTx(tx)
+		if err == nil {
+			return replaceMint(client, deposit, replacement)
+		}
+		if !errors.Is(err, evm.ErrGasCeiling) {
+			return err
+		}
+		log.WithField("prefix", "tracker").Error("ALERT: mint of deposit ", deposit.ID(), " is stuck at the gas ceiling on chain ", client.ChainID, ": ", deposit.MintTxHash)
+	}
+
+	// still waiting for inclusion, rebroadcast the same signed tx in case the node lost it
+	if time.Since(sentAt) > evm.EVM_TX_REBROADCAST_TIMEOUT {
+		if err := client.SendTx(tx); err != nil {
+			log.WithField("prefix", "tracker").Debug("Rebroadcast of ", deposit.MintTxHash, ": ", err)
+		}
+	}
+
+	return nil
+
+}
+
+// checkDropped looks for a receipt of any tx of the mint on every endpoint over several polls,
+// a re-mint could pay twice, so without a receipt the deposit is held for the operator instead
+func checkDropped(client *evm.EVMClient, deposit *store.Deposit, nonce uint64) error {
+
+	for _, txHash := range append([]string{deposit.MintTxHash}, deposit.ReplacedTxs...) {
+		receipt, err := client.GetReceiptFromAny(common.HexToHash(txHash))
+		if err != nil {
+			return err
+		}
+		// mined, the endpoint catches up on a later poll
+		if receipt != nil {
+			delete(droppedPolls, deposit.ID())
+			log.WithField("prefix", "tracker").Warn("Mint of deposit ", deposit.ID(), " mined in ", receipt.TxHash.Hex(), ", waiting for the endpoint")
+			return nil
+		}
+	}
+
+	droppedPolls[deposit.ID()]++
+	if polls := droppedPolls[deposit.ID()]; polls < TRACKER_DROPPED_POLLS {
+		log.WithField("prefix", "tracker").Warn("Mint of deposit ", deposit.ID(), " not found, nonce ", nonce, " taken by another tx, poll ", polls, " of ", TRACKER_DROPPED_POLLS)
+		return nil
+	}
+	delete(droppedPolls, deposit.ID())
+
+	log.WithField("prefix", "tracker").Error("ALERT: mint of deposit ", deposit.ID(), " dropped, nonce ", nonce, " taken by another tx and no receipt found, awaiting approval")
+	_, err := store.Ledger.HoldDeposit(deposit, "mint tx "+deposit.MintTxHash+" dropped, nonce taken by another tx")
+	return err
+
+}
+
+// findReceipt returns the receipt of the mint, any of the txs sharing the nonce may be the one mined
+func findReceipt(client *evm.EVMClient, deposit *store.Deposit) (*types.Receipt, error) {
+
+	for _, txHash := range append([]string{deposit.MintTxHash}, deposit.ReplacedTxs...) {
+		receipt, err := client.GetReceipt(common.HexToHash(txHash))
+		if err != nil {
+			return nil, err
+		}
+		if receipt != nil {
+			return receipt, nil
+		}
+	}
+
+	return nil, nil
+
+}
+
+// replaceMint records the replacement of a mint in the ledger and sends it
+func replaceMint(client *evm.EVMClient, deposit *store.Deposit, replacement *types.Transaction) error {
+
+	rawTx, err := replacement.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	// record the replacement, it is sent after
+	replaced, err := store.Ledger.UpdateDeposit(deposit, store.DepositSubmitted, func(d *store.Deposit) {
+		d.ReplacedTxs = append(d.ReplacedTxs, d.MintTxHash)
+		d.MintTxHash = replacement.Hash().Hex()
+		d.MintRawTx = rawTx
+		d.ReplacedAt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
+
+	log.WithField("prefix", "tracker").Warn("Replacing mint of deposit ", deposit.ID(), " with nonce ", replacement.Nonce(), ": ", deposit.MintTxHash, " -> ", replaced.MintTxHash)
+
+	if sendErr := client.SendTx(replacement); sendErr != nil {
+		if _, err := store.Ledger.UpdateDeposit(replaced, store.DepositSubmitted, func(d *store.Deposit) {
+			d.Error = sendErr.Error()
+		}); err != nil {
+			return err
+		}
+		return sendErr
+	}
+
+	return nil
+
+}
+
+// checkStuckNonces alerts on signer nonces that block every later mint
+func checkStuckNonces(submitted []*store.Deposit) {
+
+	checked := make(map[*evm.NonceManager]bool)
+	for _, deposit := range submitted {
+		client, err := mintClient(deposit)
+		if err != nil {
+			log.WithField("prefix", "tracker").Error(err)
+			continue
+		}
+		if checked[client.Nonces()] {
+			continue
+		}
+		checked[client.Nonces()] = true
+		nonce, stuck, err := client.Nonces().Stuck(evm.EVM_NONCE_STUCK_TIMEOUT)
+		if err != nil {
+			log.WithField("prefix", "tracker").Error("failed to check nonces on chain ", client.ChainID, ": ", err)
+			continue
+		}
+		if stuck {
+			log.WithField("prefix", "tracker").Error("ALERT: nonce ", nonce, " of ", client.PublicKey.Hex(), " on chain ", client.ChainID, " is stuck for more than ", evm.EVM_NONCE_STUCK_TIMEOUT)
+		}
+	}
+
+}
+
+// restoreNonces marks nonces of submitted mints from the ledger as in-flight
+func restoreNonces() {
+
+	for _, deposit := range store.Ledger.GetDepositsByStatus(store.DepositSubmitted) {
+		client, err := mintClient(deposit)
+		if err != nil {
+			log.WithField("prefix", "tracker").Error(err)
+			continue
+		}
+		client.Nonces().Restore(deposit.MintNonce, deposit.SubmittedAt)
+	}
+
+}
+
+// mintClient returns the client of the signer of the mint, deposits submitted before signers were recorded fall back to the tx sender
+func mintClient(deposit *store.Deposit) (*evm.EVMClient, error) {
+
+	if deposit.MintFrom != "" {
+		return store.EVM.GetClientBySigner(deposit.MintChainID, common.HexToAddress(deposit.MintFrom))
+	}
+
+	if len(deposit.MintRawTx) > 0 {
+		tx, err := evm.DecodeTx(deposit.MintRawTx)
+		if err != nil {
+			return nil, err
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, err
+		}
+		return store.EVM.GetClientBySigner(deposit.MintChainID, from)
+	}
+
+	return store.EVM.GetClientByChainId(deposit.MintChainID)
+
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AccumulatedFinance/aevm-bridge/evm"
+	"github.com/AccumulatedFinance/aevm-bridge/store"
+)
+
+const WATCHER_INTERVAL = 5 * time.Minute
+
+var unverifiedMu sync.RWMutex
+
+// unverified are bridges enforcing their expected configuration that could not be read yet, by bridgeScope.
+// Both routes of such a bridge are paused until a read succeeds, a deviation then trips the breaker.
+var unverified = make(map[string]*store.Pause)
+
+// bridgeWatch is the last seen on-chain configuration of a bridge
+type bridgeWatch struct {
+	route    *route
+	snapshot *evm.BridgeConfig // nil until the configuration was read
+}
+
+// newWatchers snapshots the on-chain configuration of the bridges of deposit routes, failed ones are retried on the next pass
+func newWatchers(routes []*route) []*bridgeWatch {
+
+	var watchers []*bridgeWatch
+	for _, r := range routes {
+		if r.direction != DIRECTION_DEPOSIT {
+			continue
+		}
+		w := &bridgeWatch{route: r}
+		if err := w.check(); err != nil {
+			if r.bridge.EnforceExpected {
+				log.WithField("prefix", "watcher").Error("failed to read bridge ", r.bridge.Address, ", paused until it is verified on the next pass: ", err)
+				unverifiedMu.Lock()
+				unverified[bridgeScope(r.bridge.Address)] = &store.Pause{
+					Scope:    bridgeScope(r.bridge.Address),
+					Reason:   "expected configuration not verified: " + err.Error(),
+					PausedAt: time.Now(),
+				}
+				unverifiedMu.Unlock()
+			} else {
+				log.WithField("prefix", "watcher").Warn("failed to read bridge ", r.bridge.Address, ", retrying on the next pass: ", err)
+			}
+		}
+		watchers = append(watchers, w)
+	}
+
+	return watchers
+
+}
+
+// watchBridges re-checks the on-chain configuration of the bridges periodically
+func watchBridges(watchers []*bridgeWatch, die chan bool) {
+
+	for {
+
+		select {
+		default:
+
+			time.Sleep(WATCHER_INTERVAL)
+
+			for _, w := range watchers {
+				if err := w.check(); err != nil {
+					log.WithField("prefix", "watcher").Error("failed to check bridge ", w.route.bridge.Address, ": ", err)
+				}
+			}
+
+		case <-die:
+			return
+		}
+
+	}
+
+}
+
+// check alerts on changes since the last snapshot and verifies the expected values, the first read only logs the configuration
+func (w *bridgeWatch) check() error {
+
+	current, err := w.route.source.GetBridgeConfig(w.route.bridge.Address)
+	if err != nil {
+		return err
+	}
+
+	if w.snapshot == nil {
+		log.WithField("prefix", "watcher").Info("Bridge ", w.route.bridge.Address, " owner=", current.Owner, " minter=", current.Minter, " stakingToken=", current.StakingToken, " wst=", current.Wst)
+	} else {
+		for _, change := range compareBridgeConfig(w.snapshot, current, "was") {
+			log.WithField("prefix", "watcher").Error("ALERT: bridge ", w.route.bridge.Address, " on chain ", w.route.chainID, " ", change)
+		}
+	}
+
+	w.snapshot = current
+	w.verify(current)
+
+	unverifiedMu.Lock()
+	delete(unverified, bridgeScope(w.route.bridge.Address))
+	unverifiedMu.Unlock()
+
+	return nil
+
+}
+
+// unverifiedPause returns the pause of a bridge whose enforced configuration was not read yet, nil if there is none
+func unverifiedPause(address string) *store.Pause {
+
+	unverifiedMu.RLock()
+	defer unverifiedMu.RUnlock()
+
+	if pause, exists := unverified[bridgeScope(address)]; exists {
+		p := *pause
+		return &p
+	}
+
+	return nil
+
+}
+
+// verify alerts on values deviating from the config and pauses the bridge if they are enforced
+func (w *bridgeWatch) verify(current *evm.BridgeConfig) {
+
+	expected := &evm.BridgeConfig{
+		Owner:        w.route.bridge.ExpectedOwner,
+		Minter:       w.route.bridge.ExpectedMinter,
+		StakingToken: w.route.bridge.ExpectedStakingToken,
+		Wst:          w.route.bridge.ExpectedWst,
+	}
+
+	for _, deviation := range compareBridgeConfig(expected, current, "expected") {
+		log.WithField("prefix", "watcher").Error("ALERT: bridge ", w.route.bridge.Address, " on chain ", w.route.chainID, " ", deviation)
+		if w.route.bridge.EnforceExpected {
+			tripBreaker(bridgeScope(w.route.bridge.Address), deviation)
+		}
+	}
+
+}
+
+// compareBridgeConfig describes values of current differing from previous as "is current, <verb> previous", empty previous values are not compared
+func compareBridgeConfig(previous *evm.BridgeConfig, current *evm.BridgeConfig, verb string) []string {
+
+	var changes []string
+	compare := func(name string, previous string, current string) {
+		if previous != "" && !strings.EqualFold(previous, current) {
+			changes = append(changes, fmt.Sprintf("%s is %s, %s %s", name, current, verb, previous))
+		}
+	}
+
+	compare("owner", previous.Owner, current.Owner)
+	compare("minter", previous.Minter, current.Minter)
+	compare("stakingToken", previous.StakingToken, current.StakingToken)
+	compare("wst", previous.Wst, current.Wst)
+
+	return changes
+
+}